@@ -192,6 +192,7 @@ func rewriteGoStmt(fset *token.FileSet, info types.Info, qual types.Qualifier, r
 var goTmpl = template.Must(template.New("").Parse(`
 go func(__f1 func(), __f2 {{.Typ}} {{range .DefArgs}},{{.}}{{end}}){
 	__f1()
+	defer {{.Imp}}.Exit()
 	__f2({{range .InnerArgs}}{{.}},{{end}})
 }({{.Imp}}.GetSpawnCallback(), {{.Func}}{{range .OuterArgs}},{{.}}{{end}})
 `))
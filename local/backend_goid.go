@@ -0,0 +1,185 @@
+//go:build !runtimelocal && !nolocal
+// +build !runtimelocal,!nolocal
+
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Backend names the goroutine-local storage
+// backend selected by build tags.
+const Backend = "goid"
+
+// persistent is set if values set with setGoroutineLocal
+// can be retrieved with getGoroutineLocal.
+const persistent = true
+
+// By default, each goroutine's locals are stored in a map keyed
+// by goroutine ID, which works with an unmodified runtime. The
+// map is sharded to reduce contention between goroutines.
+//
+// Every access to the locals parses the current goroutine's ID out of
+// its stack trace (see goid), which is considerably slower than the
+// runtimelocal backend; cache the values of locals which are read in
+// tight loops.
+//
+// Goroutines which exit without calling Exit would leave their entries
+// in the map forever, so whenever the number of entries has doubled
+// since the last sweep, the entries of goroutines which no longer exist
+// are removed (see sweep). OnGoroutineExit is not called for them.
+
+type bucket struct {
+	locals map[int64]interface{}
+	sync.RWMutex
+}
+
+var buckets = make([]bucket, 8*runtime.NumCPU())
+
+func init() {
+	for i := range buckets {
+		buckets[i].locals = make(map[int64]interface{})
+	}
+}
+
+func getBucket(goid int64) *bucket {
+	return &buckets[int(goid%int64(len(buckets)))]
+}
+
+func getGoroutineLocal() interface{} {
+	goid := goid()
+	bkt := getBucket(goid)
+	bkt.RLock()
+	l := bkt.locals[goid]
+	bkt.RUnlock()
+	return l
+}
+
+func setGoroutineLocal(l interface{}) {
+	goid := goid()
+	bkt := getBucket(goid)
+	bkt.Lock()
+	_, had := bkt.locals[goid]
+	var n int64
+	switch {
+	case l == nil && had:
+		delete(bkt.locals, goid)
+		atomic.AddInt64(&entries, -1)
+	case l != nil:
+		bkt.locals[goid] = l
+		if !had {
+			n = atomic.AddInt64(&entries, 1)
+		}
+	}
+	bkt.Unlock()
+	if n >= atomic.LoadInt64(&sweepAt) {
+		sweep()
+	}
+}
+
+// minSweep is the number of entries at which the first sweep happens.
+const minSweep = 1024
+
+var (
+	// entries is the number of entries in buckets
+	entries int64
+	// sweepAt is the number of entries at which the next sweep happens
+	sweepAt  int64 = minSweep
+	sweepMtx sync.Mutex
+)
+
+// sweep removes the entries of goroutines which have exited
+// without calling Exit. Goroutine IDs are never reused, so an
+// entry which existed before the list of live goroutines was
+// taken, and whose goroutine is not in the list, is dead.
+func sweep() {
+	sweepMtx.Lock()
+	defer sweepMtx.Unlock()
+	if atomic.LoadInt64(&entries) < atomic.LoadInt64(&sweepAt) {
+		// another goroutine has just swept
+		return
+	}
+
+	candidates := make([][]int64, len(buckets))
+	for i := range buckets {
+		bkt := &buckets[i]
+		bkt.RLock()
+		for id := range bkt.locals {
+			candidates[i] = append(candidates[i], id)
+		}
+		bkt.RUnlock()
+	}
+	live := liveGoroutines()
+
+	var removed int64
+	for i := range buckets {
+		bkt := &buckets[i]
+		bkt.Lock()
+		for _, id := range candidates[i] {
+			if _, ok := bkt.locals[id]; ok && !live[id] {
+				delete(bkt.locals, id)
+				removed++
+			}
+		}
+		bkt.Unlock()
+	}
+	n := atomic.AddInt64(&entries, -removed)
+	atomic.AddInt64(&liveEntries, -removed)
+	if n < minSweep/2 {
+		n = minSweep / 2
+	}
+	atomic.StoreInt64(&sweepAt, 2*n)
+}
+
+// liveGoroutines returns the IDs of all
+// goroutines, parsed from their stack traces.
+func liveGoroutines() map[int64]bool {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	live := make(map[int64]bool)
+	for _, line := range bytes.Split(buf, []byte("\n")) {
+		if !bytes.HasPrefix(line, goroutinePrefix) {
+			continue
+		}
+		line = line[len(goroutinePrefix):]
+		if i := bytes.IndexByte(line, ' '); i >= 0 {
+			line = line[:i]
+		}
+		if id, err := strconv.ParseInt(string(line), 10, 64); err == nil {
+			live[id] = true
+		}
+	}
+	return live
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goid returns the current goroutine's ID, which
+// is parsed from the first line of its stack trace
+// ("goroutine 1 [running]:"). Formatting the trace
+// makes it the main cost of accessing the locals.
+func goid() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		panic(fmt.Errorf("local: could not parse goroutine ID: %v", err))
+	}
+	return id
+}
@@ -0,0 +1,54 @@
+//go:build !runtimelocal && !nolocal
+// +build !runtimelocal,!nolocal
+
+package local
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestSweep(t *testing.T) {
+	tok := Register(0, Callbacks{})
+
+	// goroutines which are alive keep their locals
+	const kept = 10
+	release := make(chan struct{})
+	errs := make(chan string, kept)
+	for i := 1; i <= kept; i++ {
+		ready := make(chan struct{})
+		go func(i int) {
+			defer Exit()
+			SetLocal(tok, i)
+			close(ready)
+			<-release
+			if v := GetLocal(tok).(int); v != i {
+				errs <- "local of live goroutine was swept"
+			}
+			errs <- ""
+		}(i)
+		<-ready
+	}
+
+	// goroutines which exit without calling Exit leak their
+	// locals until they are swept
+	start := atomic.LoadInt64(&entries)
+	for i := 0; i < 4*minSweep; i++ {
+		done := make(chan struct{})
+		go func() {
+			SetLocal(tok, 1)
+			close(done)
+		}()
+		<-done
+	}
+	if leaked := atomic.LoadInt64(&entries) - start; leaked >= 2*minSweep {
+		t.Errorf("unexpected live entries after leaking goroutines: got %v; want fewer than %v", leaked, 2*minSweep)
+	}
+
+	close(release)
+	for i := 0; i < kept; i++ {
+		if err := <-errs; err != "" {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build nolocal
+// +build nolocal
+
+package local
+
+// Backend names the goroutine-local storage
+// backend selected by build tags.
+const Backend = "none"
+
+// persistent is set if values set with setGoroutineLocal
+// can be retrieved with getGoroutineLocal.
+const persistent = false
+
+// The nolocal build tag disables goroutine-local storage, for
+// platforms where neither the patched runtime nor goroutine IDs
+// are available. Each access to a goroutine's locals sees a fresh
+// copy of the initial values (as prepared for a spawned goroutine
+// by the LocalForSpawn callbacks), and changes are discarded, so
+// local variables can only be carried with a context.Context (see
+// WithLocal and FromContext).
+
+func getGoroutineLocal() interface{} { return nil }
+
+func setGoroutineLocal(l interface{}) {}
@@ -0,0 +1,28 @@
+//go:build nolocal
+// +build nolocal
+
+package local
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNoLocal(t *testing.T) {
+	tok := Register(&[]int{1}, Callbacks{
+		LocalForSpawn: func(l interface{}) interface{} {
+			n := append([]int{}, *l.(*[]int)...)
+			return &n
+		},
+	})
+	(*GetLocal(tok).(*[]int))[0] = 2
+	SetLocal(tok, &[]int{3})
+	if v := (*GetLocal(tok).(*[]int))[0]; v != 1 {
+		t.Errorf("unexpected value: got %v; want 1", v)
+	}
+
+	ctx := WithLocal(context.Background(), tok, 4)
+	if v, ok := FromContext(ctx, tok); !ok || v != 4 {
+		t.Errorf("unexpected value: got %v, %v; want 4, true", v, ok)
+	}
+}
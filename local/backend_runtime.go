@@ -0,0 +1,28 @@
+//go:build runtimelocal
+// +build runtimelocal
+
+package local
+
+import (
+	"runtime"
+)
+
+// Backend names the goroutine-local storage
+// backend selected by build tags.
+const Backend = "runtime"
+
+// persistent is set if values set with setGoroutineLocal
+// can be retrieved with getGoroutineLocal.
+const persistent = true
+
+// The runtimelocal build tag stores each goroutine's locals in
+// the slot added to the runtime's goroutine structure by
+// cmd/modify-runtime, which must have been run against GOROOT.
+
+func getGoroutineLocal() interface{} {
+	return runtime.GetLocal()
+}
+
+func setGoroutineLocal(l interface{}) {
+	runtime.SetLocal(l)
+}
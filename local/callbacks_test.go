@@ -0,0 +1,133 @@
+//go:build !nolocal
+// +build !nolocal
+
+package local
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegisterLate(t *testing.T) {
+	tok1 := Register(1, Callbacks{})
+	SetLocal(tok1, 2)
+	// registered after this goroutine's locals were initialized
+	tok2 := Register(3, Callbacks{})
+	if GetLocal(tok1) != 2 || GetLocal(tok2) != 3 {
+		t.Errorf("unexpected values: got %v, %v; want 2, 3", GetLocal(tok1), GetLocal(tok2))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok := Register(0, Callbacks{})
+			SetLocal(tok, 1)
+			if GetLocal(tok) != 1 || GetLocal(tok1) != 1 {
+				t.Errorf("unexpected values: got %v, %v; want 1, 1", GetLocal(tok), GetLocal(tok1))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestExit(t *testing.T) {
+	exited := make(chan interface{}, 1)
+	tok := Register(0, Callbacks{
+		OnGoroutineExit: func(l interface{}) {
+			// the callback stays registered, so it must
+			// not block when other tests' goroutines exit
+			select {
+			case exited <- l:
+			default:
+			}
+		},
+	})
+	SetLocal(tok, 1)
+	Go(func() {
+		SetLocal(tok, 2)
+	})
+	if l := <-exited; l != 2 {
+		t.Errorf("unexpected local on exit: got %v; want 2", l)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tok := Register([]string{}, Callbacks{
+		Merge: func(l, other interface{}) interface{} {
+			return append(append([]string{}, l.([]string)...), other.([]string)...)
+		},
+	})
+	unmerged := Register("a", Callbacks{})
+	SetLocal(tok, []string{"a"})
+
+	done := make(chan State)
+	Go(func() {
+		SetLocal(tok, []string{"b"})
+		SetLocal(unmerged, "b")
+		done <- Snapshot()
+	})
+	Join(<-done)
+	if l := GetLocal(tok).([]string); len(l) != 2 || l[0] != "a" || l[1] != "b" {
+		t.Errorf("unexpected merged local: got %v; want [a b]", l)
+	}
+	if GetLocal(unmerged) != "a" {
+		t.Errorf("unexpected unmerged local: got %v; want a", GetLocal(unmerged))
+	}
+}
+
+func TestLiveEntries(t *testing.T) {
+	tok := Register(0, Callbacks{})
+	// make sure that this goroutine has local storage
+	// before counting, since Go will initialize it
+	GetLocal(tok)
+	start := LiveEntries()
+
+	const n = 10
+	var started sync.WaitGroup
+	started.Add(n)
+	release := make(chan struct{})
+	exited := make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		Go(func() {
+			defer func() { exited <- struct{}{} }()
+			SetLocal(tok, 1)
+			started.Done()
+			<-release
+		})
+	}
+	started.Wait()
+	if got := LiveEntries(); got != start+n {
+		t.Errorf("unexpected live entries: got %v; want %v", got, start+n)
+	}
+	close(release)
+	for i := 0; i < n; i++ {
+		<-exited
+	}
+
+	// code generated by the rewrite tool
+	// calls Exit even if the goroutine panics
+	done := make(chan struct{})
+	go func(__f1 func(), __f2 func()) {
+		defer close(done)
+		defer func() { recover() }()
+		__f1()
+		defer Exit()
+		__f2()
+	}(GetSpawnCallback(), func() {
+		SetLocal(tok, 2)
+		panic("panic")
+	})
+	<-done
+
+	// f's deferred function runs before Exit, so
+	// wait for the goroutines to finish exiting
+	for i := 0; i < 100 && LiveEntries() != start; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if got := LiveEntries(); got != start {
+		t.Errorf("unexpected live entries after exit: got %v; want %v", got, start)
+	}
+}
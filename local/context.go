@@ -0,0 +1,66 @@
+package local
+
+import (
+	"context"
+)
+
+type contextKey struct{ t Token }
+
+// contextValue wraps values stored by WithLocal
+// so that nil values can be distinguished
+type contextValue struct{ v interface{} }
+
+// localsContextKey is the key for the
+// locals stored in a context by WithLocals
+type localsContextKey struct{}
+
+// WithLocal returns a copy of ctx which carries v as
+// the value of the local variable associated with the
+// given Token. It does not affect any goroutine's locals.
+func WithLocal(ctx context.Context, t Token, v interface{}) context.Context {
+	return context.WithValue(ctx, contextKey{t}, contextValue{v})
+}
+
+// FromContext returns the value of the local variable associated
+// with the given Token carried by ctx, set either with WithLocal
+// or with WithLocals (WithLocal takes precedence if ctx was derived
+// from both). If ctx carries no value for t, ok is false.
+func FromContext(ctx context.Context, t Token) (v interface{}, ok bool) {
+	if cv, ok := ctx.Value(contextKey{t}).(contextValue); ok {
+		return cv.v, true
+	}
+	if l, ok := ctx.Value(localsContextKey{}).(local); ok && int(t) < len(l) {
+		return l[t], true
+	}
+	return nil, false
+}
+
+// WithLocals returns a copy of ctx which carries the current
+// goroutine's local variables, as they would be set in a goroutine
+// spawned by the current goroutine (see Callbacks.LocalForSpawn).
+// Another goroutine can adopt them with RestoreContext, which allows
+// libraries that pass a context.Context to worker goroutines to carry
+// local variables to them.
+func WithLocals(ctx context.Context) context.Context {
+	return context.WithValue(ctx, localsContextKey{}, spawnLocal())
+}
+
+// RestoreContext sets each of the current goroutine's local variables
+// for which ctx carries a value (see FromContext) to a copy of that
+// value made with its Clone callback (see Callbacks), so that ctx may
+// be restored in any number of goroutines. It returns whether ctx
+// carried any values.
+func RestoreContext(ctx context.Context) bool {
+	// load the registry after the locals, so that it
+	// has at least as many variables as the locals
+	l := getLocal()
+	r := getRegistry()
+	restored := false
+	for i := range l {
+		if v, ok := FromContext(ctx, Token(i)); ok {
+			l[i] = r.clone(i, v)
+			restored = true
+		}
+	}
+	return restored
+}
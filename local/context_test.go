@@ -0,0 +1,72 @@
+//go:build !nolocal
+// +build !nolocal
+
+package local
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	tok := Register(0, Callbacks{
+		LocalForSpawn: func(l interface{}) interface{} { return l.(int) + 1 },
+	})
+	SetLocal(tok, 1)
+
+	ctx := WithLocals(context.Background())
+	if v, ok := FromContext(ctx, tok); !ok || v != 2 {
+		t.Errorf("unexpected value: got %v, %v; want 2, true", v, ok)
+	}
+	ctx2 := WithLocal(ctx, tok, 5)
+	if v, ok := FromContext(ctx2, tok); !ok || v != 5 {
+		t.Errorf("unexpected value: got %v, %v; want 5, true", v, ok)
+	}
+	if _, ok := FromContext(context.Background(), tok); ok {
+		t.Errorf("unexpected value in empty context")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if GetLocal(tok) != 0 {
+			t.Errorf("unexpected initial value: got %v; want 0", GetLocal(tok))
+		}
+		if !RestoreContext(ctx2) {
+			t.Errorf("unexpected false result from RestoreContext")
+		}
+		if GetLocal(tok) != 5 {
+			t.Errorf("unexpected value after RestoreContext: got %v; want 5", GetLocal(tok))
+		}
+	}()
+	<-done
+}
+
+func TestContextPointer(t *testing.T) {
+	type state struct{ n int }
+	tok := Register((*state)(nil), Callbacks{
+		Clone: func(l interface{}) interface{} {
+			if l.(*state) == nil {
+				return l
+			}
+			n := *l.(*state)
+			return &n
+		},
+	})
+	SetLocal(tok, &state{n: 1})
+	ctx := WithLocals(context.Background())
+
+	for i := 0; i < 2; i++ {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			RestoreContext(ctx)
+			if n := GetLocal(tok).(*state).n; n != 1 {
+				t.Errorf("unexpected value after RestoreContext: got %v; want 1", n)
+			}
+			// must not affect the context
+			GetLocal(tok).(*state).n = 2
+		}()
+		<-done
+	}
+}
@@ -1,14 +1,32 @@
 package local
 
 import (
-	"fmt"
-	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
 type local []interface{}
 
-var callbacks []Callbacks
-var defaults []interface{}
+// registry holds the registered local variables.
+// It is replaced rather than modified, so that
+// readers need not hold registryMtx.
+type registry struct {
+	callbacks []Callbacks
+	defaults  []interface{}
+}
+
+var (
+	registered  atomic.Value // *registry
+	registryMtx sync.Mutex   // serializes updates to registered
+)
+
+func init() {
+	registered.Store(&registry{})
+}
+
+func getRegistry() *registry {
+	return registered.Load().(*registry)
+}
 
 // Token represents a handle on a particular
 // local variable; it is used to distinguish
@@ -33,6 +51,30 @@ type Callbacks struct {
 	// is nil, the current goroutine's local is used
 	// as the initial value in the spawned goroutine.
 	LocalForSpawn func(local interface{}) interface{}
+
+	// OnGoroutineExit, if non-nil, is called with
+	// the goroutine's local when a goroutine calls
+	// Exit, such as when a goroutine spawned with
+	// Go returns.
+	OnGoroutineExit func(local interface{})
+
+	// Merge takes the current goroutine's local and
+	// the local of another goroutine which has joined
+	// it (see Join), and produces the local that should
+	// be set in the current goroutine. If Merge is nil,
+	// the current goroutine's local is left unchanged.
+	Merge func(local, other interface{}) interface{}
+
+	// Clone, if non-nil, returns a copy of a local which
+	// has been saved (see Snapshot and WithLocals), each
+	// time it is restored in a goroutine (see Restore and
+	// RestoreContext), so that changes that the goroutine
+	// makes to its copy do not affect the saved local. It
+	// may be called with the local's initial value. If
+	// Clone is nil, the saved local itself is restored,
+	// which is only safe if it is not modified in place
+	// (e.g., if it is not a pointer).
+	Clone func(local interface{}) interface{}
 }
 
 // Register registers a new local variable whose
@@ -40,12 +82,19 @@ type Callbacks struct {
 // arguments. The returned Token can be used to
 // identify the variable in future calls.
 //
-// Register should ONLY be called during initialization,
-// and in the main goroutine.
+// Register may be called at any time. Goroutines
+// which already exist see the initial value the
+// first time they access the new variable.
 func Register(initial interface{}, c Callbacks) Token {
-	defaults = append(defaults, initial)
-	callbacks = append(callbacks, c)
-	return Token(len(callbacks) - 1)
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+	old := getRegistry()
+	r := &registry{
+		callbacks: append(append([]Callbacks(nil), old.callbacks...), c),
+		defaults:  append(append([]interface{}(nil), old.defaults...), initial),
+	}
+	registered.Store(r)
+	return Token(len(r.callbacks) - 1)
 }
 
 // GetSpawnCallback returns a function which should
@@ -55,9 +104,106 @@ func Register(initial interface{}, c Callbacks) Token {
 // NOTE: This should only be called by code generated
 // with the associated rewrite tool.
 func GetSpawnCallback() func() {
+	newl := spawnLocal()
+	return func() {
+		setLocal(newl)
+	}
+}
+
+// Go runs f in a new goroutine whose local variables are
+// set as with GetSpawnCallback, and calls Exit when f
+// returns (or panics).
+func Go(f func()) {
+	go func(f1 func(), f2 func()) {
+		f1()
+		defer Exit()
+		f2()
+	}(GetSpawnCallback(), f)
+}
+
+// Exit calls the OnGoroutineExit callback of each local
+// variable with the current goroutine's value, and then
+// releases the goroutine's local storage, so that its
+// values are not retained after it exits. It should be
+// called, typically deferred, before a goroutine exits;
+// Go and code generated by the rewrite tool do so. If
+// the goroutine accesses its locals after calling Exit,
+// they are reinitialized to their initial values.
+func Exit() {
+	l, ok := getGoroutineLocal().(local)
+	if !ok {
+		return
+	}
+	// release the storage even if a callback panics
+	defer setLocal(nil)
+	r := getRegistry()
+	for i, c := range r.callbacks[:len(l)] {
+		if c.OnGoroutineExit != nil {
+			c.OnGoroutineExit(l[i])
+		}
+	}
+}
+
+var liveEntries int64
+
+// LiveEntries returns the number of goroutines which currently
+// have local storage, for debugging leaks of goroutine-local state:
+// a goroutine has local storage from the first time it accesses
+// its locals (or is spawned with GetSpawnCallback or Go) until it
+// calls Exit (or, with the default backend, until its storage is
+// reclaimed after it exits without calling Exit). With the nolocal
+// build tag, it is always 0.
+func LiveEntries() int {
+	return int(atomic.LoadInt64(&liveEntries))
+}
+
+// setLocal sets the current goroutine's locals,
+// or releases its storage if l is nil, and keeps
+// liveEntries up to date.
+func setLocal(l local) {
+	if persistent {
+		_, had := getGoroutineLocal().(local)
+		switch {
+		case had && l == nil:
+			atomic.AddInt64(&liveEntries, -1)
+		case !had && l != nil:
+			atomic.AddInt64(&liveEntries, 1)
+		}
+	}
+	if l == nil {
+		// store an untyped nil so that the goid
+		// backend removes the goroutine's entry
+		setGoroutineLocal(nil)
+	} else {
+		setGoroutineLocal(l)
+	}
+}
+
+// Join merges s, which is typically a Snapshot taken by
+// another goroutine as it finished its work, into the current
+// goroutine's local variables using their Merge callbacks.
+// It should be called when the current goroutine waits for
+// the other goroutine, for example by receiving from a channel
+// or by calling the Wait method of a sync.WaitGroup.
+func Join(s State) {
 	l := getLocal()
+	r := getRegistry()
+	for i, c := range r.callbacks[:len(l)] {
+		if c.Merge != nil && i < len(s.l) {
+			l[i] = c.Merge(l[i], s.l[i])
+		}
+	}
+}
+
+// spawnLocal returns the locals which should be set
+// in a goroutine spawned by the current goroutine.
+func spawnLocal() local {
+	// load the registry after the locals, so that it
+	// has at least as many variables as the locals
+	l := getLocal()
+	r := getRegistry()
 	newl := make(local, len(l))
-	for i, c := range callbacks {
+	for i, c := range r.callbacks[:len(l)] {
 		f := c.LocalForSpawn
 		if f == nil {
 			newl[i] = l[i]
@@ -65,10 +211,16 @@ func GetSpawnCallback() func() {
 			newl[i] = f(l[i])
 		}
 	}
+	return newl
+}
 
-	return func() {
-		runtime.SetLocal(newl)
+// clone returns a copy of v, a saved value of the
+// local variable at index i (see Callbacks.Clone).
+func (r *registry) clone(i int, v interface{}) interface{} {
+	if f := r.callbacks[i].Clone; f != nil {
+		return f(v)
 	}
+	return v
 }
 
 // GetLocal returns the local variable associated
@@ -87,16 +239,26 @@ func SetLocal(t Token, l interface{}) {
 
 // getLocal retreives this goroutine's
 // locals slice, initializing it to
-// a copy of defaults if none exists
+// a copy of defaults if none exists,
+// and extending it with the defaults
+// of variables registered since it
+// was initialized
 func getLocal() local {
-	l, ok := runtime.GetLocal().(local)
-	if !ok {
-		l = make(local, len(defaults))
-		copy(l, defaults)
-		runtime.SetLocal(l)
-	}
-	if len(l) != len(defaults) {
-		panic(fmt.Errorf("unexpected number of locals: got %v; want %v", len(l), len(defaults)))
+	r := getRegistry()
+	l, ok := getGoroutineLocal().(local)
+	if !ok || len(l) < len(r.defaults) {
+		newl := make(local, len(r.defaults))
+		copy(newl, l)
+		for i := len(l); i < len(newl); i++ {
+			newl[i] = r.defaults[i]
+			if f := r.callbacks[i].LocalForSpawn; !persistent && f != nil {
+				// the locals are discarded after use, so give
+				// each use its own copy in case it is modified
+				newl[i] = f(newl[i])
+			}
+		}
+		l = newl
+		setLocal(l)
 	}
 	return l
 }
@@ -0,0 +1,61 @@
+package local
+
+// With the nolocal build tag, goroutines have no local storage to
+// save or restore, so Snapshot only returns the initial values of
+// the locals, and Restore and Run have no effect on the locals seen
+// by later accesses; carry locals in a context.Context instead (see
+// WithLocals and RestoreContext).
+
+// State is an opaque snapshot of a goroutine's local
+// variables, obtained with Snapshot. The zero State
+// holds the initial value of each local variable.
+type State struct {
+	l local
+	// prev is set for the States returned by Restore,
+	// whose locals are restored as-is rather than cloned,
+	// since they belong to the goroutine which saved them
+	prev bool
+}
+
+// Snapshot returns the current goroutine's local variables,
+// as they would be set in a goroutine spawned by the current
+// goroutine (see Callbacks.LocalForSpawn). It is intended for
+// work which is queued and later executed by another goroutine
+// (see Run).
+func Snapshot() State {
+	return State{l: spawnLocal()}
+}
+
+// Restore sets the current goroutine's local variables to s,
+// and returns their previous values so that they can be restored
+// afterwards. A State may be restored any number of times, in
+// any number of goroutines; changes that one goroutine makes to
+// its local variables do not affect s, provided that variables
+// which are modified in place have a Clone callback.
+func Restore(s State) (prev State) {
+	prev = State{l: getLocal(), prev: true}
+	if s.l == nil {
+		// getLocal will initialize the locals to their defaults
+		setLocal(nil)
+		return prev
+	}
+	if s.prev {
+		setLocal(s.l)
+		return prev
+	}
+	l := make(local, len(s.l))
+	r := getRegistry()
+	for i, v := range s.l {
+		l[i] = r.clone(i, v)
+	}
+	setLocal(l)
+	return prev
+}
+
+// Run calls f with the current goroutine's local variables set
+// to s, and then restores their previous values, even if f panics.
+func Run(s State, f func()) {
+	prev := Restore(s)
+	defer Restore(prev)
+	f()
+}
@@ -0,0 +1,83 @@
+//go:build !nolocal
+// +build !nolocal
+
+package local
+
+import (
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	tok := Register("", Callbacks{})
+	SetLocal(tok, "submitter")
+	s := Snapshot()
+	SetLocal(tok, "changed")
+
+	work := make(chan func())
+	done := make(chan struct{})
+	go func() {
+		SetLocal(tok, "worker")
+		for f := range work {
+			f()
+		}
+		if GetLocal(tok) != "worker" {
+			t.Errorf("unexpected value after Run: got %v; want %v", GetLocal(tok), "worker")
+		}
+		close(done)
+	}()
+
+	for i := 0; i < 2; i++ {
+		work <- func() {
+			Run(s, func() {
+				if GetLocal(tok) != "submitter" {
+					t.Errorf("unexpected value in Run: got %v; want %v", GetLocal(tok), "submitter")
+				}
+				// must not affect the snapshot
+				SetLocal(tok, "modified")
+			})
+		}
+	}
+	work <- func() {
+		defer func() { recover() }()
+		Run(s, func() { panic("panic") })
+	}
+	work <- func() {
+		prev := Restore(State{})
+		if GetLocal(tok) != "" {
+			t.Errorf("unexpected value for zero State: got %v; want %q", GetLocal(tok), "")
+		}
+		Restore(prev)
+	}
+	close(work)
+	<-done
+}
+
+func TestSnapshotPointer(t *testing.T) {
+	type state struct{ n int }
+	tok := Register((*state)(nil), Callbacks{
+		Clone: func(l interface{}) interface{} {
+			if l.(*state) == nil {
+				return l
+			}
+			n := *l.(*state)
+			return &n
+		},
+	})
+	SetLocal(tok, &state{n: 1})
+	s := Snapshot()
+
+	for i := 0; i < 2; i++ {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Run(s, func() {
+				if n := GetLocal(tok).(*state).n; n != 1 {
+					t.Errorf("unexpected value in Run: got %v; want 1", n)
+				}
+				// must not affect the snapshot
+				GetLocal(tok).(*state).n = 2
+			})
+		}()
+		<-done
+	}
+}
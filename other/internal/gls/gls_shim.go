@@ -3,7 +3,6 @@
 package gls
 
 import (
-	"fmt"
 	"runtime"
 	"unsafe"
 )
@@ -14,29 +13,22 @@ func _go(f func()) {
 
 type shim func([]shim)
 
+// ptrSize is the size of a pointer in bytes. Each byte of
+// a goroutine's storage pointer is encoded in its call stack
+// as a call to the shim for that byte's value, so the shim
+// approach works on any architecture that runtime.Callers
+// supports, given its pointer size.
+const ptrSize = int(unsafe.Sizeof(uintptr(0)))
+
 func base(f func()) {
 	m := make(map[interface{}]interface{})
 	ptr := uintptr(unsafe.Pointer(&m))
 
-	var s []shim
-
-	switch runtime.GOARCH {
-	case "386":
-		s = make([]shim, 4)
-		s[0] = shims[int((ptr>>8)&0xFF)]
-		s[1] = shims[int((ptr>>16)&0xFF)]
-		s[2] = shims[int((ptr>>24)&0xFF)]
-	case "amd64":
-		s = make([]shim, 8)
-		s[0] = shims[int((ptr>>8)&0xFF)]
-		s[1] = shims[int((ptr>>16)&0xFF)]
-		s[2] = shims[int((ptr>>24)&0xFF)]
-		s[3] = shims[int((ptr>>32)&0xFF)]
-		s[4] = shims[int((ptr>>40)&0xFF)]
-		s[5] = shims[int((ptr>>48)&0xFF)]
-		s[6] = shims[int((ptr>>56)&0xFF)]
-	default:
-		panic(fmt.Errorf("gls: unsupported GOARCH %v", runtime.GOARCH))
+	// the lowest byte selects the first shim, which calls
+	// the shims for the remaining bytes in ascending order
+	s := make([]shim, ptrSize)
+	for i := 1; i < ptrSize; i++ {
+		s[i-1] = shims[int((ptr>>(8*uint(i)))&0xFF)]
 	}
 	s[len(s)-1] = func(_ []shim) { f() }
 
@@ -50,15 +42,9 @@ func base(f func()) {
 var basePtr uintptr
 
 func init() {
-	skip := 3
-	switch runtime.GOARCH {
-	case "386":
-		skip += 4
-	case "amd64":
-		skip += 8
-	default:
-		panic(fmt.Errorf("gls: unsupported GOARCH %v", runtime.GOARCH))
-	}
+	// skip runtime.Callers, f, the final
+	// closure in base and one shim per byte
+	skip := 3 + ptrSize
 	f := func() {
 		pcs := make([]uintptr, 16)
 		runtime.Callers(skip, pcs)
@@ -87,24 +73,11 @@ func getPtr() uintptr {
 	// element, not the length
 	i -= 3
 
+	// the shim for the lowest byte is the
+	// outermost, and so the last in pcs
 	var ptr uintptr
-	switch runtime.GOARCH {
-	case "386":
-		ptr |= pcToUintptr[pcs[i]]
-		ptr |= pcToUintptr[pcs[i-1]] << 8
-		ptr |= pcToUintptr[pcs[i-2]] << 16
-		ptr |= pcToUintptr[pcs[i-3]] << 24
-	case "amd64":
-		ptr |= pcToUintptr[pcs[i]]
-		ptr |= pcToUintptr[pcs[i-1]] << 8
-		ptr |= pcToUintptr[pcs[i-2]] << 16
-		ptr |= pcToUintptr[pcs[i-3]] << 24
-		ptr |= pcToUintptr[pcs[i-4]] << 32
-		ptr |= pcToUintptr[pcs[i-5]] << 40
-		ptr |= pcToUintptr[pcs[i-6]] << 48
-		ptr |= pcToUintptr[pcs[i-7]] << 56
-	default:
-		panic(fmt.Errorf("gls: unsupported GOARCH %v", runtime.GOARCH))
+	for j := 0; j < ptrSize; j++ {
+		ptr |= pcToUintptr[pcs[i-j]] << (8 * uint(j))
 	}
 
 	return ptr
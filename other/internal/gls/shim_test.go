@@ -31,3 +31,23 @@ func TestPointerResolution(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestShimFrames(t *testing.T) {
+	done := make(chan struct{})
+	Go(func() {
+		defer close(done)
+		var pcs [64]uintptr
+		n := runtime.Callers(1, pcs[:])
+		shimFrames := 0
+		for _, pc := range pcs[:n] {
+			if _, ok := pcToUintptr[pc]; ok {
+				shimFrames++
+			}
+		}
+		// one shim call per byte of the storage pointer
+		if shimFrames != ptrSize {
+			t.Errorf("unexpected number of shim frames on %v: got %v; want %v", runtime.GOARCH, shimFrames, ptrSize)
+		}
+	})
+	<-done
+}
@@ -0,0 +1,35 @@
+// Package baggage forwards to package
+// github.com/brown-csci1380/tracing-framework-go/xtrace/baggage,
+// where the baggage implementation has moved.
+//
+// Deprecated: Import xtrace/baggage instead.
+package baggage
+
+import (
+	xbaggage "github.com/brown-csci1380/tracing-framework-go/xtrace/baggage"
+)
+
+// ContextKey is xtrace/baggage.ContextKey.
+var ContextKey = xbaggage.ContextKey
+
+// ByteNamespaces is xtrace/baggage.ByteNamespaces.
+type ByteNamespaces = xbaggage.ByteNamespaces
+
+// ByteBaggage is xtrace/baggage.ByteBaggage.
+type ByteBaggage = xbaggage.ByteBaggage
+
+// Marshaler is xtrace/baggage.Marshaler.
+type Marshaler = xbaggage.Marshaler
+
+// Unmarshaler is xtrace/baggage.Unmarshaler.
+type Unmarshaler = xbaggage.Unmarshaler
+
+// Marshal calls xtrace/baggage.Marshal(v).
+func Marshal(v interface{}) ([]byte, error) {
+	return xbaggage.Marshal(v)
+}
+
+// Unmarshal calls xtrace/baggage.Unmarshal(data, v).
+func Unmarshal(data []byte, v interface{}) error {
+	return xbaggage.Unmarshal(data, v)
+}
@@ -1,13 +1,13 @@
 package baggage
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 
-	"github.com/golang/protobuf/proto"
-
-	lproto "github.com/brownsys/tracing-framework-go/trace/baggage/internal/proto"
+	lproto "github.com/brown-csci1380/tracing-framework-go/xtrace/baggage/internal/proto"
 )
 
 // ContextKey should be used as the key for baggage
@@ -44,36 +44,36 @@ type Unmarshaler interface {
 	UnmarshalBaggage(b []byte) error
 }
 
+// Marshal marshals v, which must be ByteNamespaces or of the form
+// map[T]map[U][]V, where T and U are string or implement Marshaler,
+// and V is []byte or implements Marshaler. Namespaces and bags are
+// written in order of their marshaled keys, so equal values always
+// produce the same encoding; it is an error for two namespace keys,
+// or two bag keys in the same namespace, to marshal to the same bytes.
+// A nil map is marshaled as empty, but a nil pointer is an error.
+// The encoding is truncated to the current Limits (see SetLimits).
 func Marshal(v interface{}) ([]byte, error) {
 	if bv, ok := v.(ByteNamespaces); ok {
-		var message lproto.BaggageMessage
-		message.Namespace = make([]*lproto.BaggageMessage_NamespaceData, len(bv))
-
-		i := -1
-		for k, ns := range bv {
-			i++
-			var pns lproto.BaggageMessage_NamespaceData
-			pns.Key = []byte(k)
-			pns.Bag = make([]*lproto.BaggageMessage_BagData, len(ns))
-			j := -1
-			for k, bag := range ns {
-				j++
-				var pbag lproto.BaggageMessage_BagData
-				pbag.Key = []byte(k)
-				pbag.Value = bag
-				pns.Bag[j] = &pbag
-			}
-			message.Namespace[i] = &pns
-		}
+		message := byteNamespacesMessage(bv)
+		applyLimits(message, GetLimits())
 
-		bytes, err := proto.Marshal(&message)
+		buf, err := encodeMessage(message)
 		if err != nil {
 			return nil, fmt.Errorf("baggage: Marshal: %v", err)
 		}
-		return bytes, nil
+		return buf, nil
 	}
 
 	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil, fmt.Errorf("baggage: Marshal: nil value")
+	}
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("baggage: Marshal nil %v", rv.Type())
+		}
+		rv = rv.Elem()
+	}
 	typ := rv.Type()
 
 	marshalSettingsCache.RLock()
@@ -91,32 +91,140 @@ func Marshal(v interface{}) ([]byte, error) {
 		marshalSettingsCache.Unlock()
 	}
 
-	panic("unimplemented")
+	var message lproto.BaggageMessage
+	message.Namespace = make([]*lproto.BaggageMessage_NamespaceData, 0, rv.Len())
+
+	iter := rv.MapRange()
+	for iter.Next() {
+		var pns lproto.BaggageMessage_NamespaceData
+		key, err := marshalKey(iter.Key())
+		if err != nil {
+			return nil, fmt.Errorf("baggage: Marshal: namespace key: %v", err)
+		}
+		pns.Key = key
+
+		namespace := iter.Value()
+		pns.Bag = make([]*lproto.BaggageMessage_BagData, 0, namespace.Len())
+		bagIter := namespace.MapRange()
+		for bagIter.Next() {
+			var pbag lproto.BaggageMessage_BagData
+			key, err := marshalKey(bagIter.Key())
+			if err != nil {
+				return nil, fmt.Errorf("baggage: Marshal: baggage key: %v", err)
+			}
+			pbag.Key = key
+
+			bv := bagIter.Value()
+			pbag.Value = make([][]byte, bv.Len())
+			for i := range pbag.Value {
+				elem := bv.Index(i)
+				if settings.bagElemTyp == byteSliceTyp {
+					pbag.Value[i] = elem.Bytes()
+					continue
+				}
+				b, err := elem.Interface().(Marshaler).MarshalBaggage()
+				if err != nil {
+					return nil, fmt.Errorf("baggage: Marshal: baggage element: %v", err)
+				}
+				pbag.Value[i] = b
+			}
+			pns.Bag = append(pns.Bag, &pbag)
+		}
+		sortBags(pns.Bag)
+		for i := 1; i < len(pns.Bag); i++ {
+			if bytes.Equal(pns.Bag[i-1].Key, pns.Bag[i].Key) {
+				return nil, fmt.Errorf("baggage: Marshal: duplicate baggage key %q in namespace %q", pns.Bag[i].Key, pns.Key)
+			}
+		}
+		message.Namespace = append(message.Namespace, &pns)
+	}
+	sortNamespaces(message.Namespace)
+	for i := 1; i < len(message.Namespace); i++ {
+		if bytes.Equal(message.Namespace[i-1].Key, message.Namespace[i].Key) {
+			return nil, fmt.Errorf("baggage: Marshal: duplicate namespace key %q", message.Namespace[i].Key)
+		}
+	}
+	applyLimits(&message, GetLimits())
+
+	buf, err := encodeMessage(&message)
+	if err != nil {
+		return nil, fmt.Errorf("baggage: Marshal: %v", err)
+	}
+	return buf, nil
+}
+
+// byteNamespacesMessage returns a BaggageMessage
+// representing b with sorted namespaces and bags.
+func byteNamespacesMessage(b ByteNamespaces) *lproto.BaggageMessage {
+	var message lproto.BaggageMessage
+	message.Namespace = make([]*lproto.BaggageMessage_NamespaceData, 0, len(b))
+	for k, ns := range b {
+		var pns lproto.BaggageMessage_NamespaceData
+		pns.Key = []byte(k)
+		pns.Bag = make([]*lproto.BaggageMessage_BagData, 0, len(ns))
+		for k, bag := range ns {
+			var pbag lproto.BaggageMessage_BagData
+			pbag.Key = []byte(k)
+			pbag.Value = bag
+			pns.Bag = append(pns.Bag, &pbag)
+		}
+		sortBags(pns.Bag)
+		message.Namespace = append(message.Namespace, &pns)
+	}
+	sortNamespaces(message.Namespace)
+	return &message
+}
+
+// marshalKey marshals a namespace or bag key,
+// which is either a string or a Marshaler.
+func marshalKey(v reflect.Value) ([]byte, error) {
+	if v.Kind() == reflect.String && v.Type() == stringTyp {
+		return []byte(v.String()), nil
+	}
+	return v.Interface().(Marshaler).MarshalBaggage()
 }
 
+func sortNamespaces(ns []*lproto.BaggageMessage_NamespaceData) {
+	sort.SliceStable(ns, func(i, j int) bool { return bytes.Compare(ns[i].Key, ns[j].Key) < 0 })
+}
+
+func sortBags(bags []*lproto.BaggageMessage_BagData) {
+	sort.SliceStable(bags, func(i, j int) bool { return bytes.Compare(bags[i].Key, bags[j].Key) < 0 })
+}
+
+// Unmarshal unmarshals data into v, which must be a non-nil
+// ByteNamespaces or a pointer to a non-nil map of the form described
+// for Marshal, except that T, U and V must be string, string and []byte
+// or implement Unmarshaler through a pointer. Malformed data results
+// in an error, and data exceeding the current Limits is truncated.
 func Unmarshal(data []byte, v interface{}) error {
+	if v == nil {
+		return fmt.Errorf("baggage: Unmarshal nil")
+	}
 	bv, ok1 := v.(ByteNamespaces)
 	bvp, ok2 := v.(*ByteNamespaces)
 	if ok1 || ok2 {
 		if ok2 {
+			if bvp == nil {
+				return fmt.Errorf("baggage: Unmarshal: nil *ByteNamespaces")
+			}
+			if *bvp == nil {
+				*bvp = make(ByteNamespaces)
+			}
 			bv = *bvp
 		}
+		if bv == nil {
+			return fmt.Errorf("baggage: Unmarshal: nil ByteNamespaces")
+		}
 
-		var message lproto.BaggageMessage
-
-		if err := proto.Unmarshal(data, &message); err != nil {
-			return fmt.Errorf("baggage: Unmarshal: %v", err)
+		message, err := unmarshalMessage(data)
+		if err != nil {
+			return err
 		}
 
 		for _, ns := range message.GetNamespace() {
-			if ns == nil {
-				panic("internal error")
-			}
 			bags := make(ByteBaggage)
 			for _, bag := range ns.GetBag() {
-				if bag == nil {
-					panic("internal error")
-				}
 				bags[string(bag.GetKey())] = bag.GetValue()
 			}
 			bv[string(ns.GetKey())] = bags
@@ -132,6 +240,9 @@ func Unmarshal(data []byte, v interface{}) error {
 		return fmt.Errorf("baggage: Unmarshal non-pointer %v", typ)
 	}
 
+	if rv.IsNil() {
+		return fmt.Errorf("baggage: Unmarshal nil %v", typ)
+	}
 	rv = rv.Elem()
 	typ = typ.Elem()
 
@@ -150,15 +261,20 @@ func Unmarshal(data []byte, v interface{}) error {
 		unmarshalSettingsCache.Unlock()
 	}
 
-	var message lproto.BaggageMessage
+	if rv.IsNil() {
+		return fmt.Errorf("baggage: Unmarshal: nil map")
+	}
 
-	if err := proto.Unmarshal(data, &message); err != nil {
-		return fmt.Errorf("baggage: Unmarshal: %v", err)
+	message, err := unmarshalMessage(data)
+	if err != nil {
+		return err
 	}
 
 	for _, ns := range message.GetNamespace() {
-		if ns == nil {
-			panic("internal error")
+		if string(ns.Key) == OverflowNamespace {
+			// the marker need not be a valid namespace key;
+			// use Overflowed with ByteNamespaces to detect it
+			continue
 		}
 
 		name := reflect.New(settings.namespaceNameTyp)
@@ -174,10 +290,6 @@ func Unmarshal(data []byte, v interface{}) error {
 
 		namespace := reflect.MakeMap(settings.namespaceTyp)
 		for _, bag := range ns.GetBag() {
-			if bag == nil {
-				panic("internal error")
-			}
-
 			bk := reflect.New(settings.bagNameTyp)
 			if settings.bagNameTyp == stringTyp {
 				bk.Elem().SetString(string(bag.Key))
@@ -211,6 +323,38 @@ func Unmarshal(data []byte, v interface{}) error {
 	return nil
 }
 
+// unmarshalMessage decodes and validates a BaggageMessage,
+// and truncates it to the current Limits.
+func unmarshalMessage(data []byte) (*lproto.BaggageMessage, error) {
+	var message lproto.BaggageMessage
+	if err := decodeMessage(data, &message); err != nil {
+		return nil, fmt.Errorf("baggage: Unmarshal: %v", err)
+	}
+	if err := prepareMessage(&message); err != nil {
+		return nil, fmt.Errorf("baggage: Unmarshal: %v", err)
+	}
+	return &message, nil
+}
+
+// prepareMessage validates a decoded BaggageMessage, sorts
+// its namespaces and bags and truncates it to the current Limits.
+func prepareMessage(message *lproto.BaggageMessage) error {
+	for _, ns := range message.Namespace {
+		if ns == nil {
+			return fmt.Errorf("nil namespace")
+		}
+		for _, bag := range ns.Bag {
+			if bag == nil {
+				return fmt.Errorf("nil bag in namespace %q", ns.Key)
+			}
+		}
+		sortBags(ns.Bag)
+	}
+	sortNamespaces(message.Namespace)
+	applyLimits(message, GetLimits())
+	return nil
+}
+
 var marshalSettingsCache = struct {
 	m map[reflect.Type]marshalSettings
 	sync.RWMutex
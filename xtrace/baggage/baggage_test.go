@@ -0,0 +1,234 @@
+package baggage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	lproto "github.com/brown-csci1380/tracing-framework-go/xtrace/baggage/internal/proto"
+)
+
+func TestUnmarshalBasic(t *testing.T) {
+	testCaseUnmarshal(t, nil, ByteNamespaces{})
+	testCaseUnmarshal(t, []testNamespace{{}}, ByteNamespaces{"": ByteBaggage{}})
+	testCaseUnmarshal(t, []testNamespace{{key: []byte("foo"),
+		vals: []testBag{{key: []byte("bar"), vals: [][]byte{[]byte("baz")}}}}},
+		ByteNamespaces{"foo": ByteBaggage{"bar": [][]byte{[]byte("baz")}}})
+}
+
+type unmarshalableUint32 uint32
+
+func (u *unmarshalableUint32) UnmarshalBaggage(buf []byte) error {
+	if len(buf) != 4 {
+		return fmt.Errorf("invalid buffer length: %v", len(buf))
+	}
+	*u = unmarshalableUint32(binary.BigEndian.Uint32(buf))
+	return nil
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	type tt1 map[string][]unmarshalableUint32
+	type t1 map[string]tt1
+	testCaseUnmarshal(t, nil, t1{})
+	testCaseUnmarshal(t, []testNamespace{{}}, t1{"": tt1{}})
+	testCaseUnmarshal(t, []testNamespace{{key: []byte("foo"),
+		vals: []testBag{{key: []byte("bar"), vals: [][]byte{[]byte{1, 2, 3, 4}}}}}},
+		t1{"foo": tt1{"bar": []unmarshalableUint32{0x1020304}}})
+
+	type tt2 map[unmarshalableUint32][][]byte
+	type t2 map[string]tt2
+	testCaseUnmarshal(t, nil, t2{})
+	testCaseUnmarshal(t, []testNamespace{{}}, t2{"": tt2{}})
+	testCaseUnmarshal(t, []testNamespace{{key: []byte("foo"),
+		vals: []testBag{{key: []byte{0, 1, 2, 3}, vals: [][]byte{[]byte("bar")}},
+			{key: []byte{0, 1, 2, 3}, vals: [][]byte{[]byte("baz")}}}}},
+		t2{"foo": tt2{0x10203: [][]byte{[]byte("baz")}}})
+
+	type tt3 map[string][][]byte
+	type t3 map[unmarshalableUint32]tt3
+	testCaseUnmarshal(t, nil, t3{})
+	testCaseUnmarshal(t, []testNamespace{{key: []byte{0, 1, 2, 3}, vals: []testBag{}},
+		{key: []byte{0, 1, 2, 3}, vals: []testBag{{key: []byte("foo"), vals: [][]byte{[]byte("bar")}}}}},
+		t3{0x10203: tt3{"foo": [][]byte{[]byte("bar")}}})
+}
+
+// expect must be a valid type (map[T]map[U][]V)
+func testCaseUnmarshal(t *testing.T, ns []testNamespace, expect interface{}) {
+	msg := testNamespaceToProto(ns)
+	buf, err := proto.Marshal(&msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	typ := reflect.TypeOf(expect)
+	got := reflect.New(typ)
+	got.Elem().Set(reflect.MakeMap(typ))
+	err = Unmarshal(buf, got.Interface())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	if !reflect.DeepEqual(got.Elem().Interface(), expect) {
+		t.Errorf("unexpected result: got %#v; want %#v", got.Elem().Interface(), expect)
+	}
+}
+
+type testNamespace struct {
+	key  []byte
+	vals []testBag
+}
+
+type testBag struct {
+	key  []byte
+	vals [][]byte
+}
+
+func testNamespaceToProto(t []testNamespace) lproto.BaggageMessage {
+	var msg lproto.BaggageMessage
+	for _, ns := range t {
+		var pns lproto.BaggageMessage_NamespaceData
+		// always allocate even if ns.key == nil
+		pns.Key = append([]byte{}, ns.key...)
+		for _, bag := range ns.vals {
+			var pbag lproto.BaggageMessage_BagData
+			// always allocate even if bag.key == nil
+			pbag.Key = append([]byte{}, bag.key...)
+			pbag.Value = bag.vals
+			pns.Bag = append(pns.Bag, &pbag)
+		}
+		msg.Namespace = append(msg.Namespace, &pns)
+	}
+	return msg
+}
+
+type baggageUint32 uint32
+
+func (u baggageUint32) MarshalBaggage() ([]byte, error) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(u))
+	return buf[:], nil
+}
+
+func (u *baggageUint32) UnmarshalBaggage(buf []byte) error {
+	if len(buf) != 4 {
+		return fmt.Errorf("invalid buffer length: %v", len(buf))
+	}
+	*u = baggageUint32(binary.BigEndian.Uint32(buf))
+	return nil
+}
+
+func TestMarshalOrder(t *testing.T) {
+	testCaseMarshal(t, ByteNamespaces{}, nil)
+	testCaseMarshal(t, ByteNamespaces{
+		"foo": ByteBaggage{"b": [][]byte{[]byte("1")}, "a": [][]byte{[]byte("2")}},
+		"bar": ByteBaggage{},
+	}, []testNamespace{{key: []byte("bar")},
+		{key: []byte("foo"), vals: []testBag{{key: []byte("a"), vals: [][]byte{[]byte("2")}},
+			{key: []byte("b"), vals: [][]byte{[]byte("1")}}}}})
+
+	type tt1 map[baggageUint32][]baggageUint32
+	type t1 map[string]tt1
+	testCaseMarshal(t, t1{"foo": tt1{0x10203: {5}, 0x10202: {6, 7}}},
+		[]testNamespace{{key: []byte("foo"), vals: []testBag{
+			{key: []byte{0, 1, 2, 2}, vals: [][]byte{{0, 0, 0, 6}, {0, 0, 0, 7}}},
+			{key: []byte{0, 1, 2, 3}, vals: [][]byte{{0, 0, 0, 5}}}}}})
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	testCaseRoundTrip(t, ByteNamespaces{})
+	testCaseRoundTrip(t, ByteNamespaces{"": ByteBaggage{}})
+	testCaseRoundTrip(t, ByteNamespaces{"foo": ByteBaggage{"bar": [][]byte{[]byte("baz")}}})
+
+	type tt1 map[string][]baggageUint32
+	type t1 map[string]tt1
+	testCaseRoundTrip(t, t1{})
+	testCaseRoundTrip(t, t1{"foo": tt1{"bar": []baggageUint32{1, 2}}, "baz": tt1{}})
+
+	type tt2 map[baggageUint32][][]byte
+	type t2 map[baggageUint32]tt2
+	testCaseRoundTrip(t, t2{1: tt2{2: [][]byte{[]byte("foo")}}})
+}
+
+func TestMarshalInvalidType(t *testing.T) {
+	for _, v := range []interface{}{
+		nil,
+		map[string][]byte{},
+		map[string]map[string][]int{},
+		map[int]map[string][][]byte{},
+	} {
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("unexpected nil error marshaling %T", v)
+		}
+	}
+}
+
+func TestMarshalNilPointer(t *testing.T) {
+	if _, err := Marshal((*map[string]map[string][][]byte)(nil)); err == nil {
+		t.Errorf("unexpected nil error marshaling nil pointer")
+	}
+	testCaseMarshal(t, map[string]map[string][][]byte(nil), nil)
+}
+
+// parityKey marshals only the parity of
+// its value, so distinct keys can collide
+type parityKey uint32
+
+func (k parityKey) MarshalBaggage() ([]byte, error) {
+	return []byte{byte(k % 2)}, nil
+}
+
+func TestMarshalDuplicateKeys(t *testing.T) {
+	type tt1 map[parityKey][][]byte
+	type t1 map[parityKey]tt1
+	for _, v := range []interface{}{
+		t1{1: tt1{}, 3: tt1{}},
+		t1{1: tt1{0: nil, 2: nil}},
+	} {
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("unexpected nil error marshaling %v", v)
+		}
+	}
+	testCaseMarshal(t, t1{1: tt1{0: nil, 1: nil}, 2: tt1{}},
+		[]testNamespace{{key: []byte{0}}, {key: []byte{1}, vals: []testBag{{key: []byte{0}}, {key: []byte{1}}}}})
+}
+
+// v must be a valid type (map[T]map[U][]V)
+func testCaseMarshal(t *testing.T, v interface{}, expect []testNamespace) {
+	got, err := Marshal(v)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	msg := testNamespaceToProto(expect)
+	want, err := proto.Marshal(&msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected encoding of %#v: got %v; want %v", v, got, want)
+	}
+}
+
+// v must be a valid type (map[T]map[U][]V)
+func testCaseRoundTrip(t *testing.T, v interface{}) {
+	buf, err := Marshal(v)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	typ := reflect.TypeOf(v)
+	got := reflect.New(typ)
+	got.Elem().Set(reflect.MakeMap(typ))
+	if err := Unmarshal(buf, got.Interface()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(got.Elem().Interface(), v) {
+		t.Errorf("unexpected result: got %#v; want %#v", got.Elem().Interface(), v)
+	}
+}
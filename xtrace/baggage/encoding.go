@@ -0,0 +1,90 @@
+package baggage
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	lproto "github.com/brown-csci1380/tracing-framework-go/xtrace/baggage/internal/proto"
+)
+
+// An Encoding is a wire format for baggage.
+type Encoding int
+
+const (
+	// EncodingLegacy is an unversioned BaggageMessage protocol
+	// buffer, as written by earlier versions of this package.
+	// It is the default, since all versions can read it.
+	EncodingLegacy Encoding = iota
+	// EncodingVersioned is a version header (see Version)
+	// followed by a BaggageMessage protocol buffer.
+	EncodingVersioned
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingLegacy:
+		return "legacy"
+	case EncodingVersioned:
+		return "versioned"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// Version is the version of the wire format written with
+// EncodingVersioned. Versioned baggage starts with a zero
+// byte, which cannot start a non-empty BaggageMessage, followed
+// by the version, so that Unmarshal can read both versioned
+// and legacy baggage.
+const Version = 1
+
+var encoding = struct {
+	e Encoding
+	sync.RWMutex
+}{}
+
+// SetEncoding sets the Encoding written by Marshal. Unmarshal
+// detects the Encoding of the data it reads, so it reads both
+// Encodings regardless of the setting. The Encoding applies
+// to the whole process.
+func SetEncoding(e Encoding) {
+	encoding.Lock()
+	encoding.e = e
+	encoding.Unlock()
+}
+
+// GetEncoding returns the Encoding written by Marshal.
+func GetEncoding() Encoding {
+	encoding.RLock()
+	defer encoding.RUnlock()
+	return encoding.e
+}
+
+// encodeMessage encodes message using the current Encoding.
+func encodeMessage(message *lproto.BaggageMessage) ([]byte, error) {
+	buf, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	if GetEncoding() == EncodingVersioned {
+		buf = append([]byte{0, Version}, buf...)
+	}
+	return buf, nil
+}
+
+// decodeMessage decodes message from data in either Encoding,
+// detected by the zero byte which starts versioned baggage.
+func decodeMessage(data []byte, message *lproto.BaggageMessage) error {
+	if len(data) > 0 && data[0] == 0 {
+		if len(data) < 2 {
+			return fmt.Errorf("truncated version header")
+		}
+		if data[1] != Version {
+			return fmt.Errorf("unsupported version %v", data[1])
+		}
+		data = data[2:]
+	}
+	return proto.Unmarshal(data, message)
+}
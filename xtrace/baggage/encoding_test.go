@@ -0,0 +1,95 @@
+package baggage
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// goldenBaggage is the baggage encoded in the golden files
+// in testdata, which are regenerated by running the tests with
+// -update. They were written by this package, so they guard
+// against accidental changes to the encodings, but do not show
+// compatibility with other implementations.
+var goldenBaggage = ByteNamespaces{
+	"xtrace": ByteBaggage{
+		"task":   [][]byte{{0x01, 0x02, 0x03, 0x04}},
+		"tenant": [][]byte{[]byte("a"), []byte("b")},
+	},
+	"empty": ByteBaggage{},
+	"retry": ByteBaggage{"count": [][]byte{EncodeCounter(3)}},
+}
+
+func TestEncodingGolden(t *testing.T) {
+	defer SetEncoding(GetEncoding())
+	for _, c := range []struct {
+		e    Encoding
+		file string
+	}{
+		{EncodingLegacy, "legacy.golden"},
+		{EncodingVersioned, "versioned.golden"},
+	} {
+		SetEncoding(c.e)
+		path := filepath.Join("testdata", c.file)
+		buf, err := Marshal(goldenBaggage)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if *update {
+			if err := ioutil.WriteFile(path, buf, 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		golden, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(buf, golden) {
+			t.Errorf("unexpected %v encoding: got %x; want %x", c.e, buf, golden)
+		}
+
+		got := ByteNamespaces{}
+		if err := Unmarshal(golden, got); err != nil {
+			t.Errorf("unexpected error decoding %v: %v", c.e, err)
+		} else if !reflect.DeepEqual(got, goldenBaggage) {
+			t.Errorf("unexpected %v decoding: got %v; want %v", c.e, got, goldenBaggage)
+		}
+	}
+}
+
+func TestEncodingVersions(t *testing.T) {
+	defer SetEncoding(GetEncoding())
+	SetEncoding(EncodingVersioned)
+	buf, err := Marshal(goldenBaggage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// versioned and legacy baggage can be read
+	// regardless of the Encoding being written
+	SetEncoding(EncodingLegacy)
+	got := ByteNamespaces{}
+	if err := Unmarshal(buf, got); err != nil || !reflect.DeepEqual(got, goldenBaggage) {
+		t.Errorf("unexpected result: got %v, %v; want %v, <nil>", got, err, goldenBaggage)
+	}
+	buf, err = Marshal(goldenBaggage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetEncoding(EncodingVersioned)
+	got = ByteNamespaces{}
+	if err := Unmarshal(buf, got); err != nil || !reflect.DeepEqual(got, goldenBaggage) {
+		t.Errorf("unexpected result: got %v, %v; want %v, <nil>", got, err, goldenBaggage)
+	}
+
+	for _, data := range [][]byte{{0}, {0, Version + 1}} {
+		if err := Unmarshal(data, ByteNamespaces{}); err == nil {
+			t.Errorf("unexpected nil error unmarshaling %v", data)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package baggage
+
+import (
+	"sync"
+
+	lproto "github.com/brown-csci1380/tracing-framework-go/xtrace/baggage/internal/proto"
+)
+
+// OverflowNamespace is the namespace which marks baggage that has been
+// truncated because it exceeded the Limits. It has no bags. Once baggage
+// has overflowed, the marker is kept when it is marshaled again, so that
+// downstream services can tell that their baggage may be incomplete.
+const OverflowNamespace = "__overflow"
+
+// Limits bound the size of baggage. Limits which are 0 are not enforced.
+//
+// Baggage which exceeds the limits is truncated deterministically when
+// it is marshaled or unmarshaled: namespaces and the bags in each
+// namespace are ordered by key, and only the first MaxNamespaces
+// namespaces, MaxBags bags per namespace and MaxValues values per bag
+// are kept. Then, entries are kept in that order until one would make
+// the encoding exceed MaxBytes, and it and all following entries are
+// dropped. Truncated baggage is marked with OverflowNamespace.
+type Limits struct {
+	// MaxBytes is the maximum size of marshaled baggage,
+	// including the overflow marker. It is measured in the
+	// legacy encoding; EncodingVersioned adds a two-byte
+	// version header.
+	MaxBytes int
+	// MaxNamespaces is the maximum number of namespaces.
+	MaxNamespaces int
+	// MaxBags is the maximum number of bags per namespace.
+	MaxBags int
+	// MaxValues is the maximum number of values per bag.
+	MaxValues int
+}
+
+// DefaultLimits are the Limits used unless SetLimits is called.
+// They keep marshaled baggage well within the default
+// header size limits of common RPC frameworks.
+var DefaultLimits = Limits{
+	MaxBytes:      4096,
+	MaxNamespaces: 32,
+	MaxBags:       64,
+	MaxValues:     64,
+}
+
+var limits = struct {
+	l Limits
+	sync.RWMutex
+}{l: DefaultLimits}
+
+// SetLimits sets the Limits enforced by Marshal and Unmarshal.
+func SetLimits(l Limits) {
+	limits.Lock()
+	limits.l = l
+	limits.Unlock()
+}
+
+// GetLimits returns the Limits enforced by Marshal and Unmarshal.
+func GetLimits() Limits {
+	limits.RLock()
+	defer limits.RUnlock()
+	return limits.l
+}
+
+// Overflowed returns whether b has been truncated
+// because it exceeded the Limits.
+func Overflowed(b ByteNamespaces) bool {
+	_, ok := b[OverflowNamespace]
+	return ok
+}
+
+// applyLimits truncates message, whose namespaces and bags must
+// be sorted by key, to the given Limits, as described for Limits.
+// If message has been truncated (now or before), it is marked with
+// OverflowNamespace as its last namespace.
+func applyLimits(message *lproto.BaggageMessage, l Limits) {
+	overflowed := false
+	namespaces := message.Namespace[:0]
+	for _, ns := range message.Namespace {
+		if string(ns.Key) == OverflowNamespace {
+			overflowed = true
+		} else {
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	if l.MaxNamespaces > 0 && len(namespaces) > l.MaxNamespaces {
+		namespaces, overflowed = namespaces[:l.MaxNamespaces], true
+	}
+	for _, ns := range namespaces {
+		if l.MaxBags > 0 && len(ns.Bag) > l.MaxBags {
+			ns.Bag, overflowed = ns.Bag[:l.MaxBags], true
+		}
+		for _, bag := range ns.Bag {
+			if l.MaxValues > 0 && len(bag.Value) > l.MaxValues {
+				bag.Value, overflowed = bag.Value[:l.MaxValues], true
+			}
+		}
+	}
+
+	if l.MaxBytes > 0 {
+		// leave room for the marker if it will be added anyway
+		max := l.MaxBytes
+		if overflowed {
+			max -= overflowSize
+		}
+		if messageSize(namespaces) > max {
+			namespaces = truncateBytes(namespaces, l.MaxBytes-overflowSize)
+			overflowed = true
+		}
+	}
+
+	if overflowed {
+		namespaces = append(namespaces, &lproto.BaggageMessage_NamespaceData{Key: []byte(OverflowNamespace)})
+	}
+	message.Namespace = namespaces
+}
+
+// overflowSize is the encoded size of the overflow marker.
+var overflowSize = fieldSize(fieldSize(len(OverflowNamespace)))
+
+// truncateBytes returns the longest prefix of namespaces, in the order
+// described for Limits, whose encoding takes at most max bytes. The
+// namespaces and bags in the prefix may be modified.
+func truncateBytes(namespaces []*lproto.BaggageMessage_NamespaceData, max int) []*lproto.BaggageMessage_NamespaceData {
+	// size of the completed namespaces
+	done := 0
+	for i, ns := range namespaces {
+		// size of the namespace's key and completed bags
+		nsSize := fieldSize(len(ns.Key))
+		if done+fieldSize(nsSize) > max {
+			return namespaces[:i]
+		}
+		for j, bag := range ns.Bag {
+			bagSize := fieldSize(len(bag.Key))
+			if done+fieldSize(nsSize+fieldSize(bagSize)) > max {
+				ns.Bag = ns.Bag[:j]
+				return namespaces[:i+1]
+			}
+			for k, v := range bag.Value {
+				if done+fieldSize(nsSize+fieldSize(bagSize+fieldSize(len(v)))) > max {
+					bag.Value = bag.Value[:k]
+					ns.Bag = ns.Bag[:j+1]
+					return namespaces[:i+1]
+				}
+				bagSize += fieldSize(len(v))
+			}
+			nsSize += fieldSize(bagSize)
+		}
+		done += fieldSize(nsSize)
+	}
+	return namespaces
+}
+
+// messageSize returns the encoded size of
+// a BaggageMessage with the given namespaces.
+func messageSize(namespaces []*lproto.BaggageMessage_NamespaceData) int {
+	size := 0
+	for _, ns := range namespaces {
+		nsSize := fieldSize(len(ns.Key))
+		for _, bag := range ns.Bag {
+			bagSize := fieldSize(len(bag.Key))
+			for _, v := range bag.Value {
+				bagSize += fieldSize(len(v))
+			}
+			nsSize += fieldSize(bagSize)
+		}
+		size += fieldSize(nsSize)
+	}
+	return size
+}
+
+// fieldSize returns the encoded size of a length-delimited
+// protobuf field with a one-byte tag and n bytes of data.
+func fieldSize(n int) int {
+	size := 1 + n
+	for {
+		size++
+		if n < 0x80 {
+			return size
+		}
+		n >>= 7
+	}
+}
@@ -0,0 +1,137 @@
+package baggage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	lproto "github.com/brown-csci1380/tracing-framework-go/xtrace/baggage/internal/proto"
+)
+
+func TestLimitsCounts(t *testing.T) {
+	defer SetLimits(GetLimits())
+	SetLimits(Limits{MaxNamespaces: 2, MaxBags: 1, MaxValues: 2})
+
+	b := ByteNamespaces{
+		"c": ByteBaggage{"x": [][]byte{[]byte("1")}},
+		"b": ByteBaggage{"y": [][]byte{[]byte("1")}, "x": [][]byte{[]byte("1"), []byte("2"), []byte("3")}},
+		"a": ByteBaggage{},
+	}
+	expect := ByteNamespaces{
+		"a":               ByteBaggage{},
+		"b":               ByteBaggage{"x": [][]byte{[]byte("1"), []byte("2")}},
+		OverflowNamespace: ByteBaggage{},
+	}
+	testCaseLimits(t, b, expect)
+
+	// the overflow marker is kept even if
+	// the baggage is within the limits
+	testCaseLimits(t, expect, expect)
+}
+
+func TestLimitsBytes(t *testing.T) {
+	defer SetLimits(GetLimits())
+
+	b := ByteNamespaces{
+		"a": ByteBaggage{"x": [][]byte{[]byte("1"), []byte("2")}},
+		"b": ByteBaggage{"x": [][]byte{[]byte("1")}},
+	}
+	buf, err := Marshal(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetLimits(Limits{MaxBytes: len(buf)})
+	testCaseLimits(t, b, b)
+
+	// the first entry which does not fit alongside the overflow
+	// marker (a's second value) and all following are dropped
+	SetLimits(Limits{MaxBytes: len(buf) - 1})
+	testCaseLimits(t, b, ByteNamespaces{
+		"a":               ByteBaggage{"x": [][]byte{[]byte("1")}},
+		OverflowNamespace: ByteBaggage{},
+	})
+
+	for max := 0; max <= len(buf); max++ {
+		SetLimits(Limits{MaxBytes: max + overflowSize})
+		buf, err := Marshal(b)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(buf) > max+overflowSize {
+			t.Errorf("unexpected length with MaxBytes %v: got %v", max+overflowSize, len(buf))
+		}
+	}
+}
+
+func TestLimitsBytesOverflowed(t *testing.T) {
+	defer SetLimits(GetLimits())
+
+	// baggage which has already overflowed keeps its
+	// marker, which must fit within MaxBytes as well
+	b := ByteNamespaces{
+		"a":               ByteBaggage{"x": [][]byte{[]byte("1"), []byte("2")}},
+		OverflowNamespace: ByteBaggage{},
+	}
+	SetLimits(Limits{})
+	buf, err := Marshal(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetLimits(Limits{MaxBytes: len(buf)})
+	testCaseLimits(t, b, b)
+
+	max := len(buf) - 1
+	SetLimits(Limits{MaxBytes: max})
+	buf, err = Marshal(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(buf) > max {
+		t.Errorf("unexpected length: got %v; want at most %v", len(buf), max)
+	}
+	testCaseLimits(t, b, ByteNamespaces{
+		"a":               ByteBaggage{"x": [][]byte{[]byte("1")}},
+		OverflowNamespace: ByteBaggage{},
+	})
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	for _, data := range [][]byte{
+		{0xff},
+		// namespace without required key
+		{0x0a, 0x00},
+	} {
+		if err := Unmarshal(data, ByteNamespaces{}); err == nil {
+			t.Errorf("unexpected nil error unmarshaling %v", data)
+		}
+	}
+
+	msg := lproto.BaggageMessage{Namespace: []*lproto.BaggageMessage_NamespaceData{{Key: []byte("foo")}}}
+	data, err := proto.Marshal(&msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var nilMap map[string]map[string][][]byte
+	for _, v := range []interface{}{nil, ByteNamespaces(nil), (*ByteNamespaces)(nil), &nilMap} {
+		if err := Unmarshal(data, v); err == nil {
+			t.Errorf("unexpected nil error unmarshaling into %#v", v)
+		}
+	}
+}
+
+func testCaseLimits(t *testing.T, b, expect ByteNamespaces) {
+	buf, err := Marshal(b)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	got := ByteNamespaces{}
+	if err := Unmarshal(buf, got); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected result: got %v; want %v", got, expect)
+	}
+}
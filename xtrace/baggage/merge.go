@@ -0,0 +1,185 @@
+package baggage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// A MergePolicy determines how the bags of a namespace
+// are combined when two branches of a task join.
+type MergePolicy int
+
+const (
+	// MergeUnion keeps the values of both bags, in order,
+	// omitting values of the second bag which are already
+	// present in the first. It is the default policy.
+	MergeUnion MergePolicy = iota
+	// MergeLastWriterWins replaces each bag of the first
+	// branch with the corresponding bag of the second.
+	MergeLastWriterWins
+	// MergeSum treats each bag as a counter made up of increments
+	// (see AddCounter), and keeps the increments of both branches.
+	// Increments which both branches inherited from before the task
+	// forked are kept once, so the merged counter (see CounterValue)
+	// is the sum of the increments made in each branch.
+	MergeSum
+	// MergeKeepFirst keeps each bag of the first branch,
+	// only taking bags from the second branch which the
+	// first does not have.
+	MergeKeepFirst
+)
+
+func (p MergePolicy) String() string {
+	switch p {
+	case MergeUnion:
+		return "union"
+	case MergeLastWriterWins:
+		return "last-writer-wins"
+	case MergeSum:
+		return "sum"
+	case MergeKeepFirst:
+		return "keep-first"
+	default:
+		return fmt.Sprintf("MergePolicy(%d)", int(p))
+	}
+}
+
+var mergePolicies = struct {
+	m map[string]MergePolicy
+	sync.RWMutex
+}{m: make(map[string]MergePolicy)}
+
+// RegisterMergePolicy sets the MergePolicy used by Merge
+// for the given namespace. Namespaces without a registered
+// policy use MergeUnion.
+func RegisterMergePolicy(namespace string, p MergePolicy) {
+	mergePolicies.Lock()
+	mergePolicies.m[namespace] = p
+	mergePolicies.Unlock()
+}
+
+// GetMergePolicy returns the MergePolicy
+// used by Merge for the given namespace.
+func GetMergePolicy(namespace string) MergePolicy {
+	mergePolicies.RLock()
+	defer mergePolicies.RUnlock()
+	return mergePolicies.m[namespace]
+}
+
+// Merge combines the baggage of two branches of a task, a being
+// the branch which continues (e.g., the caller of an RPC) and b the
+// branch which joins it (e.g., the RPC's response), using the policy
+// registered for each namespace. Neither a nor b is modified, but
+// the result may share bags and values with them.
+func Merge(a, b ByteNamespaces) ByteNamespaces {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	merged := make(ByteNamespaces, len(a)+len(b))
+	for k, ns := range a {
+		merged[k] = ns
+	}
+	for k, ns := range b {
+		if ans, ok := merged[k]; ok {
+			merged[k] = mergeNamespace(GetMergePolicy(k), ans, ns)
+		} else {
+			merged[k] = ns
+		}
+	}
+	return merged
+}
+
+func mergeNamespace(p MergePolicy, a, b ByteBaggage) ByteBaggage {
+	merged := make(ByteBaggage, len(a)+len(b))
+	for k, bag := range a {
+		merged[k] = bag
+	}
+	for k, bag := range b {
+		abag, ok := merged[k]
+		if !ok {
+			merged[k] = bag
+			continue
+		}
+		switch p {
+		case MergeLastWriterWins:
+			merged[k] = bag
+		case MergeKeepFirst:
+		default:
+			// increments are unique, so the union of two
+			// counters keeps each increment exactly once
+			merged[k] = unionValues(abag, bag)
+		}
+	}
+	return merged
+}
+
+func unionValues(a, b [][]byte) [][]byte {
+	union := append([][]byte(nil), a...)
+outer:
+	for _, v := range b {
+		for _, u := range union {
+			if bytes.Equal(u, v) {
+				continue outer
+			}
+		}
+		union = append(union, v)
+	}
+	return union
+}
+
+// EncodeCounter encodes n as a baggage value (a signed varint).
+func EncodeCounter(n int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append([]byte(nil), buf[:binary.PutVarint(buf[:], n)]...)
+}
+
+// DecodeCounter decodes a baggage value encoded with EncodeCounter.
+func DecodeCounter(b []byte) (int64, error) {
+	n, l := binary.Varint(b)
+	if l <= 0 || l != len(b) {
+		return 0, fmt.Errorf("baggage: DecodeCounter: invalid counter %v", b)
+	}
+	return n, nil
+}
+
+// incrementIDLen is the length of the random ID which
+// distinguishes each increment of a counter from the others
+const incrementIDLen = 8
+
+// AddCounter returns the counter bag, suitable for namespaces merged
+// with MergeSum, with an increment of n added. Each increment is a
+// separate value, so that the increments inherited by two branches of
+// a task can be told apart from those made since they forked. bag is
+// not modified.
+func AddCounter(bag [][]byte, n int64) [][]byte {
+	v := make([]byte, incrementIDLen, incrementIDLen+binary.MaxVarintLen64)
+	if _, err := rand.Read(v); err != nil {
+		panic(fmt.Errorf("baggage: could not read random bytes: %v", err))
+	}
+	v = append(v, EncodeCounter(n)...)
+	return append(bag[:len(bag):len(bag)], v)
+}
+
+// CounterValue returns the sum of the increments in the
+// counter bag (see AddCounter), or an error if any of
+// its values is not an increment.
+func CounterValue(bag [][]byte) (int64, error) {
+	var sum int64
+	for _, v := range bag {
+		if len(v) <= incrementIDLen {
+			return 0, fmt.Errorf("baggage: CounterValue: invalid increment %v", v)
+		}
+		n, err := DecodeCounter(v[incrementIDLen:])
+		if err != nil {
+			return 0, fmt.Errorf("baggage: CounterValue: invalid increment %v", v)
+		}
+		sum += n
+	}
+	return sum, nil
+}
@@ -0,0 +1,80 @@
+package baggage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	RegisterMergePolicy("lww", MergeLastWriterWins)
+	RegisterMergePolicy("first", MergeKeepFirst)
+
+	v := func(strs ...string) [][]byte {
+		var vals [][]byte
+		for _, s := range strs {
+			vals = append(vals, []byte(s))
+		}
+		return vals
+	}
+	a := ByteNamespaces{
+		"union": ByteBaggage{"x": v("1", "2"), "y": v("3")},
+		"lww":   ByteBaggage{"x": v("1"), "y": v("2")},
+		"first": ByteBaggage{"x": v("1")},
+		"a":     ByteBaggage{"x": v("1")},
+	}
+	b := ByteNamespaces{
+		"union": ByteBaggage{"x": v("2", "4"), "z": v("5")},
+		"lww":   ByteBaggage{"x": v("3")},
+		"first": ByteBaggage{"x": v("2"), "y": v("3")},
+		"b":     ByteBaggage{"x": v("1")},
+	}
+	expect := ByteNamespaces{
+		"union": ByteBaggage{"x": v("1", "2", "4"), "y": v("3"), "z": v("5")},
+		"lww":   ByteBaggage{"x": v("3"), "y": v("2")},
+		"first": ByteBaggage{"x": v("1"), "y": v("3")},
+		"a":     ByteBaggage{"x": v("1")},
+		"b":     ByteBaggage{"x": v("1")},
+	}
+	if got := Merge(a, b); !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected result: got %v; want %v", got, expect)
+	}
+	if len(a["union"]["x"]) != 2 {
+		t.Errorf("Merge modified its argument: got %v; want [1 2]", a["union"]["x"])
+	}
+}
+
+func TestMergeSum(t *testing.T) {
+	RegisterMergePolicy("sum", MergeSum)
+
+	// both branches inherit the increments made before
+	// the fork, which must only be counted once
+	forked := ByteNamespaces{"sum": ByteBaggage{"x": AddCounter(AddCounter(nil, 2), 3)}}
+	a := ByteNamespaces{"sum": ByteBaggage{"x": AddCounter(forked["sum"]["x"], 4)}}
+	b := ByteNamespaces{"sum": ByteBaggage{"x": AddCounter(AddCounter(forked["sum"]["x"], -1), 10)}}
+	merged := Merge(a, b)
+	if n, err := CounterValue(merged["sum"]["x"]); err != nil || n != 18 {
+		t.Errorf("unexpected merged counter: got %v, %v; want 18, <nil>", n, err)
+	}
+	// merging again does not count anything twice
+	if n, err := CounterValue(Merge(merged, b)["sum"]["x"]); err != nil || n != 18 {
+		t.Errorf("unexpected remerged counter: got %v, %v; want 18, <nil>", n, err)
+	}
+	if n, err := CounterValue(a["sum"]["x"]); err != nil || n != 9 {
+		t.Errorf("unexpected counter: got %v, %v; want 9, <nil>", n, err)
+	}
+	if _, err := CounterValue([][]byte{EncodeCounter(1)}); err == nil {
+		t.Errorf("unexpected nil error summing invalid increment")
+	}
+}
+
+func TestCounter(t *testing.T) {
+	for _, n := range []int64{0, 1, -1, 1 << 40} {
+		got, err := DecodeCounter(EncodeCounter(n))
+		if err != nil || got != n {
+			t.Errorf("unexpected result: got %v, %v; want %v, <nil>", got, err, n)
+		}
+	}
+	if _, err := DecodeCounter(nil); err == nil {
+		t.Errorf("unexpected nil error decoding empty counter")
+	}
+}
@@ -0,0 +1,345 @@
+package baggage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MarshalStruct marshals v, which must be a struct or a pointer to one.
+// Each field with a tag of the form `baggage:"namespace,bag"` is stored
+// in the given bag of the given namespace; no two fields may have the
+// same tag. Fields without such a tag are ignored.
+//
+// A field may be a string, an integer, a []byte or a type which
+// implements Marshaler, which is stored as a single value, or a slice
+// of any of these, which is stored as one value per element (an empty
+// slice is not stored). Integers are stored as varints (signed integers
+// as with EncodeCounter). A type whose value or pointer implements
+// Marshaler must have a pointer which implements Unmarshaler, and vice
+// versa, so that every field can be unmarshaled as it was marshaled.
+//
+// The tagged fields of embedded structs are treated as fields of the
+// outer struct. Embedded pointers to structs are not supported.
+func MarshalStruct(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("baggage: MarshalStruct nil %v", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("baggage: MarshalStruct non-struct %T", v)
+	}
+	if !rv.CanAddr() {
+		// fields may implement Marshaler through a pointer
+		addr := reflect.New(rv.Type())
+		addr.Elem().Set(rv)
+		rv = addr.Elem()
+	}
+	settings, err := getStructSettings(rv.Type())
+	if err != nil {
+		return nil, fmt.Errorf("baggage: MarshalStruct: %v", err)
+	}
+
+	b := make(ByteNamespaces)
+	for _, f := range settings {
+		fv := rv.FieldByIndex(f.index)
+		var vals [][]byte
+		if f.slice {
+			for i := 0; i < fv.Len(); i++ {
+				val, err := f.kind.marshal(fv.Index(i))
+				if err != nil {
+					return nil, fmt.Errorf("baggage: MarshalStruct: field %v: %v", f.name, err)
+				}
+				vals = append(vals, val)
+			}
+			if len(vals) == 0 {
+				continue
+			}
+		} else {
+			val, err := f.kind.marshal(fv)
+			if err != nil {
+				return nil, fmt.Errorf("baggage: MarshalStruct: field %v: %v", f.name, err)
+			}
+			vals = [][]byte{val}
+		}
+		if b[f.namespace] == nil {
+			b[f.namespace] = make(ByteBaggage)
+		}
+		b[f.namespace][f.bag] = vals
+	}
+	return Marshal(b)
+}
+
+// UnmarshalStruct unmarshals data into v, which must be a pointer
+// to a struct whose fields are tagged as described for MarshalStruct.
+// Fields whose bags are not present in data are left unchanged. If a
+// bag for a field which is not a slice has multiple values, the first
+// is used.
+func UnmarshalStruct(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("baggage: UnmarshalStruct non-pointer-to-struct %T", v)
+	}
+	rv = rv.Elem()
+	settings, err := getStructSettings(rv.Type())
+	if err != nil {
+		return fmt.Errorf("baggage: UnmarshalStruct: %v", err)
+	}
+
+	b := make(ByteNamespaces)
+	if err := Unmarshal(data, b); err != nil {
+		return err
+	}
+	for _, f := range settings {
+		vals, ok := b[f.namespace][f.bag]
+		if !ok {
+			continue
+		}
+		fv := rv.FieldByIndex(f.index)
+		if f.slice {
+			sv := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+			for i, val := range vals {
+				if err := f.kind.unmarshal(sv.Index(i), val); err != nil {
+					return fmt.Errorf("baggage: UnmarshalStruct: field %v: %v", f.name, err)
+				}
+			}
+			fv.Set(sv)
+			continue
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		if err := f.kind.unmarshal(fv, vals[0]); err != nil {
+			return fmt.Errorf("baggage: UnmarshalStruct: field %v: %v", f.name, err)
+		}
+	}
+	return nil
+}
+
+var structSettingsCache = struct {
+	m map[reflect.Type][]structField
+	sync.RWMutex
+}{m: make(map[reflect.Type][]structField)}
+
+type structField struct {
+	name      string
+	index     []int
+	namespace string
+	bag       string
+	kind      valueKind
+	// slice is set if the field is a slice of kind
+	slice bool
+}
+
+// valueKind is the kind of a value stored
+// in a bag by MarshalStruct.
+type valueKind int
+
+const (
+	kindNone valueKind = iota
+	kindString
+	kindInt
+	kindUint
+	kindBytes
+	kindCustom
+)
+
+func getStructSettings(t reflect.Type) ([]structField, error) {
+	structSettingsCache.RLock()
+	settings, ok := structSettingsCache.m[t]
+	structSettingsCache.RUnlock()
+	if ok {
+		return settings, nil
+	}
+
+	settings, err := makeStructSettings(t)
+	if err != nil {
+		return nil, err
+	}
+
+	structSettingsCache.Lock()
+	structSettingsCache.m[t] = settings
+	structSettingsCache.Unlock()
+	return settings, nil
+}
+
+func makeStructSettings(t reflect.Type) ([]structField, error) {
+	var settings []structField
+	// fields by namespace and bag, to detect
+	// fields which would overwrite each other
+	bags := make(map[[2]string]string)
+	if err := appendStructFields(&settings, bags, t, nil); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// appendStructFields appends the tagged fields of t, which is
+// at the given index in the outer struct, to settings.
+func appendStructFields(settings *[]structField, bags map[[2]string]string, t reflect.Type, index []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("baggage")
+		if tag == "-" {
+			continue
+		}
+		sf.Index = append(index[:len(index):len(index)], sf.Index...)
+		if sf.Anonymous && !ok {
+			switch {
+			case sf.Type.Kind() == reflect.Struct:
+				if err := appendStructFields(settings, bags, sf.Type, sf.Index); err != nil {
+					return err
+				}
+			case sf.Type.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Struct:
+				if hasBaggageTags(sf.Type.Elem(), make(map[reflect.Type]bool)) {
+					return fmt.Errorf("field %v: embedded pointer to struct with baggage tags", sf.Name)
+				}
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if sf.PkgPath != "" {
+			return fmt.Errorf("field %v: unexported field has baggage tag", sf.Name)
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts) != 2 {
+			return fmt.Errorf("field %v: tag must be of the form \"namespace,bag\": %q", sf.Name, tag)
+		}
+
+		if other, ok := bags[[2]string{parts[0], parts[1]}]; ok {
+			return fmt.Errorf("field %v: tag %q duplicates tag of field %v", sf.Name, tag, other)
+		}
+		bags[[2]string{parts[0], parts[1]}] = sf.Name
+
+		f := structField{name: sf.Name, index: sf.Index, namespace: parts[0], bag: parts[1]}
+		kind, err := getValueKind(sf.Type)
+		if err == nil && kind == kindNone && sf.Type.Kind() == reflect.Slice {
+			kind, err = getValueKind(sf.Type.Elem())
+			f.slice = true
+		}
+		if err != nil {
+			return fmt.Errorf("field %v: %v", sf.Name, err)
+		}
+		if kind == kindNone {
+			return fmt.Errorf("field %v: unsupported type %v", sf.Name, sf.Type)
+		}
+		f.kind = kind
+		*settings = append(*settings, f)
+	}
+	return nil
+}
+
+// hasBaggageTags returns whether the struct type t, or any
+// struct embedded in it, has fields with baggage tags.
+func hasBaggageTags(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if tag, ok := sf.Tag.Lookup("baggage"); ok && tag != "-" {
+			return true
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && ft.Kind() == reflect.Struct && hasBaggageTags(ft, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// getValueKind returns the kind of values of type t, or
+// kindNone if t is not supported. It is an error for t to
+// implement only one of Marshaler and Unmarshaler.
+func getValueKind(t reflect.Type) (valueKind, error) {
+	marshaler := t.Implements(marshalerTyp) || reflect.PtrTo(t).Implements(marshalerTyp)
+	unmarshaler := reflect.PtrTo(t).Implements(unmarshalerTyp)
+	switch {
+	case marshaler && unmarshaler:
+		return kindCustom, nil
+	case marshaler:
+		return kindNone, fmt.Errorf("type %v implements Marshaler but not Unmarshaler", t)
+	case unmarshaler:
+		return kindNone, fmt.Errorf("type %v implements Unmarshaler but not Marshaler", t)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return kindString, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return kindInt, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindUint, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return kindBytes, nil
+		}
+	}
+	return kindNone, nil
+}
+
+func (k valueKind) marshal(v reflect.Value) ([]byte, error) {
+	switch k {
+	case kindString:
+		return []byte(v.String()), nil
+	case kindInt:
+		return EncodeCounter(v.Int()), nil
+	case kindUint:
+		var buf [binary.MaxVarintLen64]byte
+		return append([]byte(nil), buf[:binary.PutUvarint(buf[:], v.Uint())]...), nil
+	case kindBytes:
+		return append([]byte(nil), v.Bytes()...), nil
+	default:
+		// v is addressable (see MarshalStruct), so that
+		// MarshalBaggage may have a pointer receiver
+		m, ok := v.Addr().Interface().(Marshaler)
+		if !ok {
+			return nil, fmt.Errorf("type %v does not implement Marshaler", v.Type())
+		}
+		return m.MarshalBaggage()
+	}
+}
+
+func (k valueKind) unmarshal(v reflect.Value, b []byte) error {
+	switch k {
+	case kindString:
+		v.SetString(string(b))
+	case kindInt:
+		n, err := DecodeCounter(b)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("value %v overflows %v", n, v.Type())
+		}
+		v.SetInt(n)
+	case kindUint:
+		n, l := binary.Uvarint(b)
+		if l <= 0 || l != len(b) {
+			return fmt.Errorf("invalid unsigned integer %v", b)
+		}
+		if v.OverflowUint(n) {
+			return fmt.Errorf("value %v overflows %v", n, v.Type())
+		}
+		v.SetUint(n)
+	case kindBytes:
+		v.SetBytes(append([]byte(nil), b...))
+	default:
+		u, ok := v.Addr().Interface().(Unmarshaler)
+		if !ok {
+			return fmt.Errorf("type %v does not implement Unmarshaler", v.Type())
+		}
+		return u.UnmarshalBaggage(b)
+	}
+	return nil
+}
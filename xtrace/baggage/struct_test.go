@@ -0,0 +1,205 @@
+package baggage
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testStruct struct {
+	Name    string          `baggage:"req,name"`
+	Retries int             `baggage:"req,retries"`
+	Shard   uint16          `baggage:"req,shard"`
+	Token   []byte          `baggage:"auth,token"`
+	Path    []string        `baggage:"req,path"`
+	IDs     []baggageUint32 `baggage:"req,ids"`
+	Owner   baggageUint32   `baggage:"auth,owner"`
+	Ignored string
+	Skipped string `baggage:"-"`
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	v := testStruct{
+		Name:    "foo",
+		Retries: -3,
+		Shard:   300,
+		Token:   []byte{1, 2, 3},
+		Path:    []string{"a", "b"},
+		IDs:     []baggageUint32{1, 0x10203},
+		Owner:   7,
+		Ignored: "ignored",
+		Skipped: "skipped",
+	}
+	buf, err := MarshalStruct(&v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got testStruct
+	if err := UnmarshalStruct(buf, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v.Ignored, v.Skipped = "", ""
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("unexpected result: got %+v; want %+v", got, v)
+	}
+
+	b := ByteNamespaces{}
+	if err := Unmarshal(buf, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, err := DecodeCounter(b["req"]["retries"][0]); err != nil || n != -3 {
+		t.Errorf("unexpected counter: got %v, %v; want -3, <nil>", n, err)
+	}
+	if _, ok := b["req"]["Ignored"]; ok {
+		t.Errorf("unexpected bag for untagged field")
+	}
+}
+
+// pointerUint32 implements Marshaler through a pointer
+type pointerUint32 uint32
+
+func (u *pointerUint32) MarshalBaggage() ([]byte, error) {
+	return baggageUint32(*u).MarshalBaggage()
+}
+
+func (u *pointerUint32) UnmarshalBaggage(buf []byte) error {
+	return (*baggageUint32)(u).UnmarshalBaggage(buf)
+}
+
+type marshalOnly string
+
+func (m marshalOnly) MarshalBaggage() ([]byte, error) {
+	return []byte(m), nil
+}
+
+type unmarshalOnly string
+
+func (u *unmarshalOnly) UnmarshalBaggage(buf []byte) error {
+	*u = unmarshalOnly(buf)
+	return nil
+}
+
+type embedded struct {
+	Name string `baggage:"req,name"`
+}
+
+func TestStructPointerMarshaler(t *testing.T) {
+	type pointerStruct struct {
+		ID  pointerUint32   `baggage:"req,id"`
+		IDs []pointerUint32 `baggage:"req,ids"`
+	}
+	v := pointerStruct{ID: 1, IDs: []pointerUint32{2, 3}}
+	// fields are marshaled through pointers
+	// even if v is not passed by pointer
+	buf, err := MarshalStruct(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got pointerStruct
+	if err := UnmarshalStruct(buf, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("unexpected result: got %+v; want %+v", got, v)
+	}
+}
+
+func TestStructEmbedded(t *testing.T) {
+	type outer struct {
+		embedded
+		Retries int `baggage:"req,retries"`
+	}
+	v := outer{embedded{Name: "foo"}, 3}
+	buf, err := MarshalStruct(&v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := ByteNamespaces{}
+	if err := Unmarshal(buf, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name := b["req"]["name"]; len(name) != 1 || string(name[0]) != "foo" {
+		t.Errorf("unexpected bag for embedded field: got %q; want [foo]", name)
+	}
+	var got outer
+	if err := UnmarshalStruct(buf, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != v {
+		t.Errorf("unexpected result: got %+v; want %+v", got, v)
+	}
+}
+
+func TestStructMissingBags(t *testing.T) {
+	buf, err := Marshal(ByteNamespaces{"req": ByteBaggage{"name": [][]byte{[]byte("foo")}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := testStruct{Retries: 5, Path: []string{"a"}}
+	if err := UnmarshalStruct(buf, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect := testStruct{Name: "foo", Retries: 5, Path: []string{"a"}}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected result: got %+v; want %+v", got, expect)
+	}
+}
+
+func TestStructInvalid(t *testing.T) {
+	for _, v := range []interface{}{
+		nil,
+		5,
+		(*testStruct)(nil),
+		struct {
+			F float64 `baggage:"a,b"`
+		}{},
+		struct {
+			F string `baggage:"a"`
+		}{},
+		struct {
+			f string `baggage:"a,b"`
+		}{},
+		struct {
+			F string `baggage:"a,b"`
+			G []byte `baggage:"a,b"`
+		}{},
+		struct {
+			F marshalOnly `baggage:"a,b"`
+		}{},
+		struct {
+			F []unmarshalOnly `baggage:"a,b"`
+		}{},
+		struct {
+			*embedded
+		}{&embedded{}},
+		struct {
+			embedded
+			G string `baggage:"req,name"`
+		}{},
+	} {
+		if _, err := MarshalStruct(v); err == nil {
+			t.Errorf("unexpected nil error marshaling %#v", v)
+		}
+	}
+
+	buf, err := Marshal(ByteNamespaces{"req": ByteBaggage{"shard": [][]byte{EncodeCounter(-1)}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var v struct {
+		Shard int8 `baggage:"req,shard"`
+	}
+	if err := UnmarshalStruct(buf, v); err == nil {
+		t.Errorf("unexpected nil error unmarshaling into non-pointer")
+	}
+	if err := UnmarshalStruct(buf, &v); err != nil || v.Shard != -1 {
+		t.Errorf("unexpected result: got %v, %v; want -1, <nil>", v.Shard, err)
+	}
+	buf, err = Marshal(ByteNamespaces{"req": ByteBaggage{"shard": [][]byte{EncodeCounter(1000)}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := UnmarshalStruct(buf, &v); err == nil {
+		t.Errorf("unexpected nil error unmarshaling overflowing integer")
+	}
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SetTenantClass sets the tenant class of the current goroutine's task.
+// It is recorded in every report logged for the task, and propagates
+// with the task's other X-Trace state to spawned goroutines (see XGo)
+// and, through RPCMetadata, to other services, so that trace data and
+// resource usage can be attributed per tenant. A tenant class of 0
+// means that the task has no tenant class.
+func SetTenantClass(class int32) {
+	getLocal().tenantClass = class
+}
+
+// GetTenantClass gets the tenant class of the current goroutine's
+// task, or 0 if it has none.
+func GetTenantClass() int32 {
+	return getLocal().tenantClass
+}
+
+// SetAgent sets the agent recorded in reports logged through t,
+// which identifies the component that generated them. Agents set
+// for individual packages with SetPackageAgent take precedence.
+// If no agent is set, reports do not record an agent.
+func (t *Tracer) SetAgent(agent string) {
+	t.mtx.Lock()
+	t.agent = agent
+	t.mtx.Unlock()
+}
+
+// SetAgent calls DefaultTracer.SetAgent(agent).
+func SetAgent(agent string) {
+	DefaultTracer.SetAgent(agent)
+}
+
+type agentConfig struct {
+	packages map[string]string
+	// cache maps PCs to the agent for the package
+	// containing them, or "" if there is none
+	cache sync.Map
+}
+
+var (
+	agents    atomic.Value // *agentConfig
+	agentsMtx sync.Mutex   // serializes updates to agents
+)
+
+func init() {
+	agents.Store(&agentConfig{})
+}
+
+// SetPackageAgent sets the agent recorded in reports logged by the
+// package with the given import path (or by any package under that
+// path, if pkg ends in "/..."), regardless of which Tracer they are
+// logged through. A report logged by a package is one whose call
+// stack, at the time it is logged, contains a function in the package;
+// if several such packages have agents, the innermost one is used.
+func SetPackageAgent(pkg, agent string) {
+	agentsMtx.Lock()
+	defer agentsMtx.Unlock()
+	old := agents.Load().(*agentConfig)
+	packages := make(map[string]string, len(old.packages)+1)
+	for k, v := range old.packages {
+		packages[k] = v
+	}
+	packages[pkg] = agent
+	agents.Store(&agentConfig{packages: packages})
+}
+
+// packageAgent returns the agent for the innermost
+// function on the current call stack whose package
+// has an agent, or "" if there is none.
+func packageAgent() string {
+	cfg := agents.Load().(*agentConfig)
+	if len(cfg.packages) == 0 {
+		return ""
+	}
+
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for _, pc := range pcs[:n] {
+		if agent, ok := cfg.cache.Load(pc); ok {
+			if a := agent.(string); a != "" {
+				return a
+			}
+			continue
+		}
+		var agent string
+		if frame, _ := runtime.CallersFrames([]uintptr{pc}).Next(); frame.Function != "" {
+			agent = cfg.packageAgent(funcPackage(frame.Function))
+		}
+		cfg.cache.Store(pc, agent)
+		if agent != "" {
+			return agent
+		}
+	}
+	return ""
+}
+
+// packageAgent returns the agent for the given package.
+// An exact match takes precedence over the longest matching
+// "/..." pattern.
+func (cfg *agentConfig) packageAgent(pkg string) string {
+	if agent, ok := cfg.packages[pkg]; ok {
+		return agent
+	}
+	agent, best := "", -1
+	for pattern, a := range cfg.packages {
+		if n := matchPackagePattern(pattern, pkg); n > best {
+			agent, best = a, n
+		}
+	}
+	return agent
+}
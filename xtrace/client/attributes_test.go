@@ -0,0 +1,34 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestAttributes(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	NewTask()
+	SetTenantClass(7)
+	tr.SetAgent("component")
+	tr.Log("foo")
+	SetPackageAgent("github.com/brown-csci1380/tracing-framework-go/xtrace/...", "package")
+	defer agents.Store(&agentConfig{})
+	tr.Log("bar")
+
+	if len(sink.Reports()) != 2 {
+		t.Fatalf("unexpected number of reports: got %v; want 2", len(sink.Reports()))
+	}
+	for i, agent := range []string{"component", "package"} {
+		r := sink.Reports()[i]
+		if r.Agent != agent || r.TenantClass != 7 {
+			t.Errorf("unexpected agent and tenant class: got %q:%v; want %q:7", r.Agent, r.TenantClass, agent)
+		}
+	}
+	if md := GetRPCMetadata(); md.TenantClass != 7 {
+		t.Errorf("unexpected tenant class in RPC metadata: got %v; want 7", md.TenantClass)
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/baggage"
+)
+
+// BaggageNamespace is the baggage namespace in which
+// the values set with SetBaggage are stored.
+const BaggageNamespace = "xtrace"
+
+// SetBaggage sets the baggage value for the given key in the current
+// goroutine's X-Trace context. Baggage propagates with the rest of the
+// context to spawned goroutines (see XGo) and, through RPCMetadata, to
+// other services, so that per-request data can flow across services.
+func SetBaggage(key, value string) {
+	SetBaggageValues(BaggageNamespace, key, [][]byte{[]byte(value)})
+}
+
+// GetBaggage gets the baggage value for the given key in
+// the current goroutine's X-Trace context. If the key has
+// multiple values, the first is returned.
+func GetBaggage(key string) (value string, ok bool) {
+	vals := GetBaggageValues(BaggageNamespace, key)
+	if len(vals) == 0 {
+		return "", false
+	}
+	return string(vals[0]), true
+}
+
+// DeleteBaggage removes the baggage value for the given
+// key from the current goroutine's X-Trace context.
+func DeleteBaggage(key string) {
+	SetBaggageValues(BaggageNamespace, key, nil)
+}
+
+// SetBaggageValues sets the values of the given bag in the given
+// namespace of the current goroutine's baggage. If vals is empty,
+// the bag is removed.
+func SetBaggageValues(namespace, bag string, vals [][]byte) {
+	l := getLocal()
+	// the baggage may be shared with other goroutines,
+	// so it is copied rather than modified in place
+	b := cloneBaggage(l.baggage)
+	ns := make(baggage.ByteBaggage, len(b[namespace])+1)
+	for k, v := range b[namespace] {
+		ns[k] = v
+	}
+	if len(vals) == 0 {
+		delete(ns, bag)
+	} else {
+		ns[bag] = append([][]byte(nil), vals...)
+	}
+	if len(ns) == 0 {
+		delete(b, namespace)
+	} else {
+		b[namespace] = ns
+	}
+	l.baggage = b
+}
+
+// GetBaggageValues gets the values of the given bag in the given
+// namespace of the current goroutine's baggage. The returned slice
+// must not be modified.
+func GetBaggageValues(namespace, bag string) [][]byte {
+	return getLocal().baggage[namespace][bag]
+}
+
+// GetBaggageNamespaces returns a copy of the current goroutine's baggage.
+func GetBaggageNamespaces() baggage.ByteNamespaces {
+	b := cloneBaggage(getLocal().baggage)
+	for name, ns := range b {
+		nns := make(baggage.ByteBaggage, len(ns))
+		for k, v := range ns {
+			nns[k] = append([][]byte(nil), v...)
+		}
+		b[name] = nns
+	}
+	return b
+}
+
+// SetBaggageNamespaces replaces the current goroutine's baggage with b.
+// b must not be modified afterwards.
+func SetBaggageNamespaces(b baggage.ByteNamespaces) {
+	getLocal().baggage = b
+}
+
+// cloneBaggage returns a shallow copy of b which can
+// be modified without affecting b's namespaces map.
+func cloneBaggage(b baggage.ByteNamespaces) baggage.ByteNamespaces {
+	c := make(baggage.ByteNamespaces, len(b)+1)
+	for k, v := range b {
+		c[k] = v
+	}
+	return c
+}
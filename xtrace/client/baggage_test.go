@@ -0,0 +1,71 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBaggage(t *testing.T) {
+	NewTask()
+	SetBaggage("foo", "bar")
+
+	done := make(chan struct{})
+	XGo(func() {
+		if v, ok := GetBaggage("foo"); !ok || v != "bar" {
+			t.Errorf("unexpected baggage in child: got %q, %v; want %q, true", v, ok, "bar")
+		}
+		SetBaggage("foo", "baz")
+		close(done)
+	})
+	<-done
+	if v, _ := GetBaggage("foo"); v != "bar" {
+		t.Errorf("unexpected baggage after child modified it: got %q; want %q", v, "bar")
+	}
+
+	md := GetRPCMetadata()
+	NewTask()
+	if _, ok := GetBaggage("foo"); ok {
+		t.Errorf("unexpected baggage in new task")
+	}
+	RPCReceived(md, "received")
+	if v, _ := GetBaggage("foo"); v != "bar" {
+		t.Errorf("unexpected baggage after RPCReceived: got %q; want %q", v, "bar")
+	}
+}
+
+func TestBaggageMerge(t *testing.T) {
+	NewTask()
+	SetBaggage("foo", "bar")
+	md := GetRPCMetadata()
+
+	NewTask()
+	SetBaggage("foo", "baz")
+	RPCReturned(md, "returned")
+	got := GetBaggageValues(BaggageNamespace, "foo")
+	if len(got) != 2 || string(got[0]) != "baz" || string(got[1]) != "bar" {
+		t.Errorf("unexpected baggage after RPCReturned: got %q; want [baz bar]", got)
+	}
+
+	ch := make(chan struct{})
+	done := make(chan struct{})
+	XGo(func() {
+		SetBaggage("qux", "quux")
+		SendChannelEvent(ch)
+		ch <- struct{}{}
+		close(done)
+	})
+	<-ch
+	<-done
+	// SendChannelEvent delivers the sender's event
+	// asynchronously, so it may not be available yet
+	for i := 0; i < 100; i++ {
+		ReadChannelEvent(ch)
+		if _, ok := GetBaggage("qux"); ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if v, _ := GetBaggage("qux"); v != "quux" {
+		t.Errorf("unexpected baggage after ReadChannelEvent: got %q; want %q", v, "quux")
+	}
+}
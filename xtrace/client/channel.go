@@ -2,33 +2,82 @@ package client
 
 import (
 	"sync"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/baggage"
 )
 
-var registeredChannels map[interface{}]chan int64 = make(map[interface{}]chan int64)
+// channelEvent is sent by SendChannelEvent to the receiver of a
+// value, carrying the sender's event ID and baggage with it
+type channelEvent struct {
+	eventID int64
+	baggage baggage.ByteNamespaces
+}
+
+// channelReceiver is a receiver registered with
+// RegisterChannelReciever for a channel
+type channelReceiver struct {
+	events chan int64
+	// done is closed when the receiver is unregistered
+	done chan struct{}
+}
+
+var registeredChannels map[interface{}]chan channelEvent = make(map[interface{}]chan channelEvent)
+var registeredRecievers map[interface{}]*channelReceiver = make(map[interface{}]*channelReceiver)
 var rcLock sync.Mutex
 
 const BUF = 2
 
 // called by the reciever of a value across a channel to find out what event sent the value
 // the argument 'channel' should be any channel the goroutine is waiting to recieve a value
-// from. returns a chan int64 which will emit every eventid that has registered as sending
-// along the channel
+// from. returns a chan int64 which will emit the eventid of every sender which calls
+// SendChannelEvent on the channel until the receiver is unregistered with
+// UnregisterChannelReciever. While a receiver is registered, senders' events are only
+// delivered on the returned channel: they are not seen by ReadChannelEvent or
+// GetChannelSender, and their senders' baggage is dropped; use ReadChannelEvent to
+// propagate baggage.
 func RegisterChannelReciever(channel interface{}) (ch chan int64) {
 	rcLock.Lock()
 	defer rcLock.Unlock()
+	r, ok := registeredRecievers[channel]
+	if !ok {
+		r = &channelReceiver{events: make(chan int64, BUF), done: make(chan struct{})}
+		registeredRecievers[channel] = r
+	}
+	return r.events
+}
+
+// UnregisterChannelReciever unregisters the receiver registered
+// for channel with RegisterChannelReciever, if any. Events which
+// have not yet been delivered to the receiver are dropped, and
+// events sent later are seen by ReadChannelEvent and
+// GetChannelSender. The receiver's channel is not closed.
+func UnregisterChannelReciever(channel interface{}) {
+	rcLock.Lock()
+	defer rcLock.Unlock()
+	if r, ok := registeredRecievers[channel]; ok {
+		delete(registeredRecievers, channel)
+		close(r.done)
+	}
+}
+
+// registeredChannel returns the channel on which events sent
+// along channel are delivered. rcLock must be held.
+func registeredChannel(channel interface{}) chan channelEvent {
 	ch, ok := registeredChannels[channel]
 	if !ok {
-		ch = make(chan int64, BUF)
+		ch = make(chan channelEvent, BUF)
 		registeredChannels[channel] = ch
 	}
-	return
+	return ch
 }
 
 // Convenience method. Get the event id of the last sender in the channel, and
-// add it to the local store of redundant edges
+// add it to the local store of redundant edges. The sender's baggage is merged
+// into the current goroutine's baggage (see baggage.Merge).
 func ReadChannelEvent(channel interface{}) {
-	redund := GetChannelSender(channel)
+	redund, b := channelSender(channel)
 	AddRedundancies(redund...)
+	getLocal().baggage = baggage.Merge(getLocal().baggage, b)
 }
 
 // Get the last EventID that sent a value along the provided channel.
@@ -36,15 +85,24 @@ func ReadChannelEvent(channel interface{}) {
 // so long as the sender called called SendChannelEvent before sending
 // the value. Returns an empty slice if no sender is known.
 func GetChannelSender(channel interface{}) []int64 {
-	ch := RegisterChannelReciever(channel)
+	senders, _ := channelSender(channel)
+	return senders
+}
+
+// channelSender is like GetChannelSender,
+// but also returns the sender's baggage.
+func channelSender(channel interface{}) ([]int64, baggage.ByteNamespaces) {
+	rcLock.Lock()
+	ch := registeredChannel(channel)
+	rcLock.Unlock()
 
 	select {
 	// if the recv blocks, there must have been no call to SendChannelEvent on the
 	// channel as the function argument
-	case sender := <-ch:
-		return []int64{sender}
+	case e := <-ch:
+		return []int64{e.eventID}, e.baggage
 	default:
-		return []int64{}
+		return []int64{}, nil
 	}
 }
 
@@ -52,16 +110,30 @@ func GetChannelSender(channel interface{}) []int64 {
 // Informs the future recipient of the value which event ID it originated from.
 func SendChannelEvent(channel interface{}) {
 	rcLock.Lock()
-	defer rcLock.Unlock()
-	ch, ok := registeredChannels[channel]
-
+	r, ok := registeredRecievers[channel]
+	var ch chan channelEvent
 	if !ok {
-		ch = make(chan int64, BUF)
-		registeredChannels[channel] = ch
+		ch = registeredChannel(channel)
 	}
-	eventID := GetEventID()
+	rcLock.Unlock()
+
+	if ok {
+		id := GetEventID()
+		// the send gives up if the receiver is unregistered
+		go func() {
+			select {
+			case r.events <- id:
+			case <-r.done:
+			}
+		}()
+		return
+	}
+
+	// baggage is never modified in place, so it can be
+	// shared with the receiver without copying it
+	e := channelEvent{eventID: GetEventID(), baggage: getLocal().baggage}
 	// do this in a separate goroutine because chan sends can block unless there is a reciever ready
 	go func() {
-		ch <- eventID
+		ch <- e
 	}()
 }
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestChannelBaggage(t *testing.T) {
+	// two values sent from the same event each
+	// carry the baggage the sender had at the time
+	NewTask()
+	ch := make(chan struct{})
+	SetBaggage("v", "1")
+	SendChannelEvent(ch)
+	SetBaggage("v", "2")
+	SendChannelEvent(ch)
+
+	var got []string
+	for i := 0; i < 1000 && len(got) < 2; i++ {
+		senders, b := channelSender(ch)
+		if len(senders) == 0 {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		for _, v := range b[BaggageNamespace]["v"] {
+			got = append(got, string(v))
+		}
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("unexpected baggage received: got %q; want [1 2]", got)
+	}
+}
+
+func TestChannelReciever(t *testing.T) {
+	NewTask()
+	ch := make(chan struct{})
+	events := RegisterChannelReciever(ch)
+	if again := RegisterChannelReciever(ch); again != events {
+		t.Errorf("registering a second receiver returned a different channel")
+	}
+
+	SendChannelEvent(ch)
+	select {
+	case id := <-events:
+		if id != GetEventID() {
+			t.Errorf("unexpected event received: got %v; want %v", id, GetEventID())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the registered receiver")
+	}
+	// events for a registered receiver are
+	// only delivered to the receiver
+	SendChannelEvent(ch)
+	time.Sleep(10 * time.Millisecond)
+	if senders := GetChannelSender(ch); len(senders) != 0 {
+		t.Errorf("unexpected senders while a receiver is registered: got %v; want []", senders)
+	}
+
+	UnregisterChannelReciever(ch)
+	SendChannelEvent(ch)
+	var senders []int64
+	for i := 0; i < 1000 && len(senders) == 0; i++ {
+		if senders = GetChannelSender(ch); len(senders) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if len(senders) != 1 || senders[0] != GetEventID() {
+		t.Errorf("unexpected senders after unregistering: got %v; want [%v]", senders, GetEventID())
+	}
+}
@@ -0,0 +1,34 @@
+package client
+
+// A Field is a custom key/value pair recorded in a report.
+type Field struct {
+	Key, Value string
+}
+
+// LogFields logs the given message at Level l, recording fields
+// as custom key/value pairs in the report, in addition to the
+// Level itself (under the key "level"). Events at LevelError or
+// above are tagged with ErrorTag.
+//
+// Unlike Debug, Info, Warn and Error, LogFields does not check
+// whether l is enabled; it is intended for adapters from other
+// logging packages, which should check using Enabled or EnabledAt.
+func (t *Tracer) LogFields(l Level, str string, fields ...Field) {
+	var tags []string
+	if l >= LevelError {
+		tags = []string{ErrorTag}
+	}
+	keys := make([]string, 1, len(fields)+1)
+	values := make([]string, 1, len(fields)+1)
+	keys[0], values[0] = "level", l.String()
+	for _, f := range fields {
+		keys = append(keys, f.Key)
+		values = append(values, f.Value)
+	}
+	t.logReport(str, PopRedundancies(), tags, keys, values)
+}
+
+// LogFields calls DefaultTracer.LogFields(l, str, fields...).
+func LogFields(l Level, str string, fields ...Field) {
+	DefaultTracer.LogFields(l, str, fields...)
+}
@@ -0,0 +1,363 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A Level is the severity of a logged event. Events logged
+// with Debug, Info, Warn and Error (and their formatting
+// variants) are only reported if their Level is at least the
+// minimum Level for the calling package (see SetLevel and
+// SetPackageLevel). Events logged with Log are always reported.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// ParseLevel parses the name of a Level
+// ("debug", "info", "warn" or "error").
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown level %q", s)
+	}
+}
+
+// LevelEnvVar is the environment variable from which the minimum
+// Levels are read at initialization. Its value is a comma-separated
+// list whose elements are either a Level, which sets the global
+// minimum Level, or pkg=level, which sets the minimum Level for
+// the package with import path pkg. If pkg ends in "/...", the
+// Level applies to all packages under that path. For example:
+//
+//	XTRACE_LEVEL=warn,github.com/foo/bar=debug,github.com/foo/baz/...=error
+const LevelEnvVar = "XTRACE_LEVEL"
+
+type levelConfig struct {
+	min      Level
+	packages map[string]Level
+	// cache maps caller PCs to the minimum
+	// Level for the caller's package
+	cache sync.Map
+}
+
+var (
+	levels    atomic.Value // *levelConfig
+	levelsMtx sync.Mutex   // serializes updates to levels
+)
+
+func init() {
+	levels.Store(&levelConfig{min: LevelInfo})
+	if env := os.Getenv(LevelEnvVar); env != "" {
+		if err := SetLevels(env); err != nil {
+			fmt.Fprintf(os.Stderr, "xtrace: %v: %v\n", LevelEnvVar, err)
+		}
+	}
+}
+
+// SetLevel sets the global minimum Level, which
+// applies to all packages without an override.
+// The default is LevelInfo.
+func SetLevel(l Level) {
+	levelsMtx.Lock()
+	defer levelsMtx.Unlock()
+	old := levels.Load().(*levelConfig)
+	levels.Store(&levelConfig{min: l, packages: old.packages})
+}
+
+// SetPackageLevel sets the minimum Level for the package with the
+// given import path, overriding the global minimum Level. If pkg
+// ends in "/...", the Level applies to all packages under that path.
+func SetPackageLevel(pkg string, l Level) {
+	levelsMtx.Lock()
+	defer levelsMtx.Unlock()
+	old := levels.Load().(*levelConfig)
+	packages := make(map[string]Level, len(old.packages)+1)
+	for k, v := range old.packages {
+		packages[k] = v
+	}
+	packages[pkg] = l
+	levels.Store(&levelConfig{min: old.min, packages: packages})
+}
+
+// ClearPackageLevels removes all per-package overrides.
+func ClearPackageLevels() {
+	levelsMtx.Lock()
+	defer levelsMtx.Unlock()
+	old := levels.Load().(*levelConfig)
+	levels.Store(&levelConfig{min: old.min})
+}
+
+// SetLevels sets the global and per-package minimum Levels from
+// a specification in the format described for LevelEnvVar.
+// Per-package overrides not mentioned in spec are left unchanged.
+// If spec is invalid, SetLevels returns an error and no Levels
+// are changed.
+func SetLevels(spec string) error {
+	var min *Level
+	packages := make(map[string]Level)
+	for _, elem := range strings.Split(spec, ",") {
+		elem = strings.TrimSpace(elem)
+		if elem == "" {
+			continue
+		}
+		i := strings.LastIndex(elem, "=")
+		if i < 0 {
+			l, err := ParseLevel(elem)
+			if err != nil {
+				return err
+			}
+			min = &l
+			continue
+		}
+		l, err := ParseLevel(elem[i+1:])
+		if err != nil {
+			return err
+		}
+		packages[strings.TrimSpace(elem[:i])] = l
+	}
+
+	levelsMtx.Lock()
+	defer levelsMtx.Unlock()
+	old := levels.Load().(*levelConfig)
+	cfg := &levelConfig{min: old.min, packages: make(map[string]Level, len(old.packages)+len(packages))}
+	if min != nil {
+		cfg.min = *min
+	}
+	for k, v := range old.packages {
+		cfg.packages[k] = v
+	}
+	for k, v := range packages {
+		cfg.packages[k] = v
+	}
+	levels.Store(cfg)
+	return nil
+}
+
+// Enabled returns whether events logged at Level l
+// by the calling function would be reported.
+func Enabled(l Level) bool {
+	return levelEnabled(l, 1)
+}
+
+// levelEnabled returns whether events logged at Level l by the
+// caller skip frames above levelEnabled's caller would be reported.
+func levelEnabled(l Level, skip int) bool {
+	cfg := levels.Load().(*levelConfig)
+	if len(cfg.packages) == 0 {
+		return l >= cfg.min
+	}
+
+	var pc [1]uintptr
+	if runtime.Callers(skip+2, pc[:]) < 1 {
+		return l >= cfg.min
+	}
+	return cfg.enabledAt(pc[0], l)
+}
+
+// EnabledAt returns whether events logged at Level l by the function
+// containing the given program counter would be reported. It is
+// intended for adapters from other logging packages which record
+// the program counter of the original logging call. If pc is 0, only
+// the global minimum Level is considered.
+func EnabledAt(pc uintptr, l Level) bool {
+	cfg := levels.Load().(*levelConfig)
+	if len(cfg.packages) == 0 || pc == 0 {
+		return l >= cfg.min
+	}
+	return cfg.enabledAt(pc, l)
+}
+
+// EnabledAnywhere returns whether events logged at Level l would
+// be reported for some caller, considering both the global minimum
+// Level and every per-package override. It is intended for adapters
+// which must decide whether to build an event before the caller of
+// the original logging call is known.
+func EnabledAnywhere(l Level) bool {
+	cfg := levels.Load().(*levelConfig)
+	if l >= cfg.min {
+		return true
+	}
+	for _, min := range cfg.packages {
+		if l >= min {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg *levelConfig) enabledAt(pc uintptr, l Level) bool {
+	if min, ok := cfg.cache.Load(pc); ok {
+		return l >= min.(Level)
+	}
+	min := cfg.min
+	if frame, _ := runtime.CallersFrames([]uintptr{pc}).Next(); frame.Function != "" {
+		min = cfg.packageLevel(funcPackage(frame.Function))
+	}
+	cfg.cache.Store(pc, min)
+	return l >= min
+}
+
+// packageLevel returns the minimum Level for the given package.
+// An exact match takes precedence over the longest matching
+// "/..." pattern.
+func (cfg *levelConfig) packageLevel(pkg string) Level {
+	if l, ok := cfg.packages[pkg]; ok {
+		return l
+	}
+	min, best := cfg.min, -1
+	for pattern, l := range cfg.packages {
+		if n := matchPackagePattern(pattern, pkg); n > best {
+			min, best = l, n
+		}
+	}
+	return min
+}
+
+// matchPackagePattern returns, if pattern is of the form "path/..."
+// and pkg is path or a package under it, the length of path (so that
+// longer, more specific matches can be preferred). Otherwise, it
+// returns -1.
+func matchPackagePattern(pattern, pkg string) int {
+	if !strings.HasSuffix(pattern, "/...") {
+		return -1
+	}
+	prefix := strings.TrimSuffix(pattern, "/...")
+	if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+		return len(prefix)
+	}
+	return -1
+}
+
+// funcPackage returns the import path of the package
+// containing the function with the given fully-qualified
+// name (as returned by runtime.Frame.Function).
+func funcPackage(name string) string {
+	// the linker escapes dots in the last element of the
+	// import path (e.g., "gopkg.in/yaml%2ev2.Marshal"), so
+	// the first dot after the last slash ends the path
+	slash := strings.LastIndex(name, "/")
+	pkg := name
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		pkg = name[:slash+1+dot]
+	}
+	if unescaped, err := url.PathUnescape(pkg); err == nil {
+		return unescaped
+	}
+	return pkg
+}
+
+// logLevel logs the given message at Level l on behalf of the caller
+// skip frames above logLevel's caller, if l is enabled for that caller.
+func (t *Tracer) logLevel(skip int, l Level, str string) {
+	if !levelEnabled(l, skip+1) {
+		return
+	}
+	t.LogFields(l, str)
+}
+
+// Debug logs the given message at LevelDebug.
+func (t *Tracer) Debug(str string) { t.logLevel(1, LevelDebug, str) }
+
+// Info logs the given message at LevelInfo.
+func (t *Tracer) Info(str string) { t.logLevel(1, LevelInfo, str) }
+
+// Warn logs the given message at LevelWarn.
+func (t *Tracer) Warn(str string) { t.logLevel(1, LevelWarn, str) }
+
+// Error logs the given message at LevelError.
+// The event is tagged with ErrorTag.
+func (t *Tracer) Error(str string) { t.logLevel(1, LevelError, str) }
+
+// Debugf is like Debug, but formats its arguments as with fmt.Sprintf.
+// The arguments are not formatted if LevelDebug is disabled.
+func (t *Tracer) Debugf(format string, args ...interface{}) {
+	t.logLevelf(1, LevelDebug, format, args)
+}
+
+// Infof is like Info, but formats its arguments as with fmt.Sprintf.
+func (t *Tracer) Infof(format string, args ...interface{}) {
+	t.logLevelf(1, LevelInfo, format, args)
+}
+
+// Warnf is like Warn, but formats its arguments as with fmt.Sprintf.
+func (t *Tracer) Warnf(format string, args ...interface{}) {
+	t.logLevelf(1, LevelWarn, format, args)
+}
+
+// Errorf is like Error, but formats its arguments as with fmt.Sprintf.
+func (t *Tracer) Errorf(format string, args ...interface{}) {
+	t.logLevelf(1, LevelError, format, args)
+}
+
+func (t *Tracer) logLevelf(skip int, l Level, format string, args []interface{}) {
+	if !levelEnabled(l, skip+1) {
+		return
+	}
+	t.LogFields(l, fmt.Sprintf(format, args...))
+}
+
+// Debug calls DefaultTracer.Debug(str).
+func Debug(str string) { DefaultTracer.logLevel(1, LevelDebug, str) }
+
+// Info calls DefaultTracer.Info(str).
+func Info(str string) { DefaultTracer.logLevel(1, LevelInfo, str) }
+
+// Warn calls DefaultTracer.Warn(str).
+func Warn(str string) { DefaultTracer.logLevel(1, LevelWarn, str) }
+
+// Error calls DefaultTracer.Error(str).
+func Error(str string) { DefaultTracer.logLevel(1, LevelError, str) }
+
+// Debugf calls DefaultTracer.Debugf(format, args...).
+func Debugf(format string, args ...interface{}) {
+	DefaultTracer.logLevelf(1, LevelDebug, format, args)
+}
+
+// Infof calls DefaultTracer.Infof(format, args...).
+func Infof(format string, args ...interface{}) {
+	DefaultTracer.logLevelf(1, LevelInfo, format, args)
+}
+
+// Warnf calls DefaultTracer.Warnf(format, args...).
+func Warnf(format string, args ...interface{}) {
+	DefaultTracer.logLevelf(1, LevelWarn, format, args)
+}
+
+// Errorf calls DefaultTracer.Errorf(format, args...).
+func Errorf(format string, args ...interface{}) {
+	DefaultTracer.logLevelf(1, LevelError, format, args)
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestFuncPackage(t *testing.T) {
+	cases := map[string]string{
+		"main.main":                          "main",
+		"github.com/foo/bar.Baz":             "github.com/foo/bar",
+		"github.com/foo/bar.(*T).Method":     "github.com/foo/bar",
+		"github.com/foo/bar.Func.func1":      "github.com/foo/bar",
+		"github.com/foo/bar.v2/baz.(T).Meth": "github.com/foo/bar.v2/baz",
+		"gopkg.in/yaml%2ev2.Marshal":         "gopkg.in/yaml.v2",
+		"gopkg.in/yaml%2ev2.(*T).Method":     "gopkg.in/yaml.v2",
+	}
+	for name, expect := range cases {
+		if got := funcPackage(name); got != expect {
+			t.Errorf("unexpected package for %v: got %v; want %v", name, got, expect)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	defer SetLevel(LevelInfo)
+	defer ClearPackageLevels()
+
+	if err := SetLevels("warn,github.com/foo/...=debug,github.com/foo/bar=error"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := levels.Load().(*levelConfig)
+	cases := map[string]Level{
+		"main":                   LevelWarn,
+		"github.com/foo":         LevelDebug,
+		"github.com/foo/baz":     LevelDebug,
+		"github.com/foo/bar":     LevelError,
+		"github.com/foobar/quux": LevelWarn,
+	}
+	for pkg, expect := range cases {
+		if got := cfg.packageLevel(pkg); got != expect {
+			t.Errorf("unexpected level for %v: got %v; want %v", pkg, got, expect)
+		}
+	}
+
+	pkg := funcPackage("github.com/brown-csci1380/tracing-framework-go/xtrace/client.TestLevels")
+	SetPackageLevel(pkg, LevelError)
+	if Enabled(LevelWarn) || !Enabled(LevelError) {
+		t.Errorf("package level not applied to caller")
+	}
+
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+	NewTask()
+	tr.Warn("foo")
+	tr.Errorf("bar %v", 1)
+	if len(sink.Reports()) != 1 {
+		t.Fatalf("unexpected number of reports: got %v; want 1", len(sink.Reports()))
+	}
+	r := sink.Reports()[0]
+	if r.Label != "bar 1" || len(r.Fields) != 1 || r.Fields[0].Value != "error" {
+		t.Errorf("unexpected report: %v", r)
+	}
+}
+
+func TestSetLevelsInvalid(t *testing.T) {
+	defer SetLevel(LevelInfo)
+	defer ClearPackageLevels()
+
+	if err := SetLevels("github.com/foo=debug"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := SetLevels("error,github.com/foo=warn,github.com/bar=bogus"); err == nil {
+		t.Fatalf("unexpected nil error for invalid spec")
+	}
+	cfg := levels.Load().(*levelConfig)
+	if cfg.min != LevelInfo || len(cfg.packages) != 1 || cfg.packages["github.com/foo"] != LevelDebug {
+		t.Errorf("invalid spec partially applied: min %v, packages %v", cfg.min, cfg.packages)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"github.com/brown-csci1380/tracing-framework-go/local"
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/baggage"
 )
 
 var token local.Token
@@ -14,40 +15,103 @@ type localStorage struct {
 	eventID      int64
 	redundancies []int64
 	tags         []string
+	sampled      bool
+	forced       bool
+	task         *taskState
+	span         *Span
+	tenantClass  int32
+	taskTags     []string
+	// baggage is never modified in place, since
+	// it is shared with spawned goroutines
+	baggage baggage.ByteNamespaces
 }
 
 // exported type for RPC calls
 type RPCMetadata struct {
 	TaskID int64
 	Events []int64
+	// Unsampled is set if the task was not
+	// selected for tracing when it was created.
+	Unsampled bool
+	// Forced is set if tracing has been
+	// forced for the task (see ForceTrace).
+	Forced bool
+	// TenantClass is the task's tenant class
+	// (see SetTenantClass).
+	TenantClass int32
+	// Tags are the task's sticky tags
+	// (see AddTaskTags).
+	Tags []string
+	// Baggage is the task's baggage (see SetBaggage).
+	// It must not be modified.
+	Baggage baggage.ByteNamespaces
 }
 
 func init() {
-	token = local.Register(&localStorage{
-		taskID:       randInt64(),
-		eventID:      randInt64(),
-		redundancies: []int64{},
-	}, local.Callbacks{
-		func(l interface{}) interface{} {
+	// the initial value is nil, so that each goroutine which
+	// was not spawned by a traced goroutine gets its own
+	// localStorage (see getLocal) rather than sharing one
+	token = local.Register((*localStorage)(nil), local.Callbacks{
+		LocalForSpawn: func(l interface{}) interface{} {
+			if l.(*localStorage) == nil {
+				return l
+			}
 			// deep copy l
 			n := *(l.(*localStorage))
 			n.redundancies = []int64{}
 			return &n
 		},
+		Merge: func(l, other interface{}) interface{} {
+			cur, o := l.(*localStorage), other.(*localStorage)
+			if cur == nil || o == nil || cur.taskID != o.taskID {
+				return cur
+			}
+			// the other goroutine's last event
+			// becomes a parent of the next event
+			n := *cur
+			n.redundancies = append(append(append([]int64{}, cur.redundancies...), o.eventID), o.redundancies...)
+			n.taskTags = unionTags(cur.taskTags, o.taskTags)
+			n.baggage = baggage.Merge(cur.baggage, o.baggage)
+			return &n
+		},
+		Clone: func(l interface{}) interface{} {
+			if l.(*localStorage) == nil {
+				return l
+			}
+			// copy the slices which are appended to in place;
+			// baggage is never modified in place
+			n := *(l.(*localStorage))
+			n.redundancies = append([]int64{}, n.redundancies...)
+			n.tags = append([]string(nil), n.tags...)
+			n.taskTags = append([]string(nil), n.taskTags...)
+			return &n
+		},
 	})
 }
 
+func newLocalStorage() *localStorage {
+	return &localStorage{
+		taskID:       randInt64(),
+		eventID:      randInt64(),
+		redundancies: []int64{},
+		sampled:      true,
+	}
+}
+
 // Runs the given function in a new goroutine, but copies the
-// local vars from the current goroutine first.
+// local vars from the current goroutine first. XGo calls
+// DefaultTracer.XGo(f).
 func XGo(f func()) {
-	go func(f1 func(), f2 func()) {
-		f1()
-		f2()
-	}(local.GetSpawnCallback(), f)
+	DefaultTracer.XGo(f)
 }
 
 func getLocal() *localStorage {
-	return local.GetLocal(token).(*localStorage)
+	l := local.GetLocal(token).(*localStorage)
+	if l == nil {
+		l = newLocalStorage()
+		local.SetLocal(token, l)
+	}
+	return l
 }
 
 func GetRPCMetadata() RPCMetadata {
@@ -55,6 +119,11 @@ func GetRPCMetadata() RPCMetadata {
 	var r RPCMetadata
 	r.Events = append(l.redundancies, l.eventID)
 	r.TaskID = l.taskID
+	r.Unsampled = !l.sampled
+	r.Forced = l.forced
+	r.TenantClass = l.tenantClass
+	r.Tags = l.taskTags
+	r.Baggage = l.baggage
 	return r
 }
 
@@ -62,10 +131,20 @@ func (r *RPCMetadata) Set() {
 	l := getLocal()
 	r.Events = append(l.redundancies, l.eventID)
 	r.TaskID = l.taskID
+	r.Unsampled = !l.sampled
+	r.Forced = l.forced
+	r.TenantClass = l.tenantClass
+	r.Tags = l.taskTags
+	r.Baggage = l.baggage
 }
 
 func RPCReceived(r RPCMetadata, msg string) {
 	SetTaskID(r.TaskID)
+	getLocal().sampled = !r.Unsampled
+	getLocal().forced = r.Forced
+	getLocal().tenantClass = r.TenantClass
+	getLocal().taskTags = unionTags(nil, r.Tags)
+	getLocal().baggage = r.Baggage
 	events := r.Events
 	if len(events) >= 1 {
 		SetEventID(events[0])
@@ -77,6 +156,8 @@ func RPCReceived(r RPCMetadata, msg string) {
 func RPCReturned(r RPCMetadata, msg string) {
 	SetTaskID(r.TaskID)
 	AddRedundancies(r.Events...)
+	getLocal().taskTags = unionTags(getLocal().taskTags, r.Tags)
+	getLocal().baggage = baggage.Merge(getLocal().baggage, r.Baggage)
 	Log(msg)
 }
 
@@ -94,24 +175,41 @@ func SetEventID(eventID int64) {
 // This should be used when propagating Task IDs over RPC
 // calls or other channels.
 //
+// If taskID differs from the current Task ID, the task is
+// considered to have started in this process now (see
+// TailPolicy and SetTaskSummaries).
+//
 // WARNING: This will overwrite any previous Task ID,
 // so call with caution.
 func SetTaskID(taskID int64) {
-	getLocal().taskID = taskID
+	l := getLocal()
+	if taskID != l.taskID || l.task == nil {
+		l.task = newTaskState()
+	}
+	l.taskID = taskID
 }
 
+// AddTags is equivalent to AddEventTags.
 func AddTags(str ...string) {
-	if getLocal().tags == nil {
-		getLocal().tags = str
-	} else {
-		getLocal().tags = append(getLocal().tags, str...)
-	}
+	AddEventTags(str...)
 }
 
+// NewTask starts a new task in the current goroutine.
+// Whether the task is traced is decided by the Sampler
+// set with SetSampler. The given tags become the task's
+// sticky tags (see AddTaskTags).
 func NewTask(tags ...string) {
 	SetTaskID(randInt64())
 	SetEventID(randInt64())
-	getLocal().tags = tags
+	getLocal().tags = nil
+	getLocal().taskTags = unionTags(nil, tags)
+	getLocal().sampled = sample(tags)
+	getLocal().forced = false
+	// spans of the previous task must not
+	// become parents of the new task's spans
+	getLocal().span = nil
+	getLocal().tenantClass = 0
+	getLocal().baggage = nil
 }
 
 // GetEventID gets the current goroutine's X-Trace Event ID.
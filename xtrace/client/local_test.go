@@ -0,0 +1,68 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/local"
+)
+
+func TestJoin(t *testing.T) {
+	NewTask()
+	PopRedundancies()
+	done := make(chan local.State)
+	var childEvent int64
+	XGo(func() {
+		SetBaggage("joined", "1")
+		childEvent = GetEventID()
+		done <- local.Snapshot()
+	})
+	local.Join(<-done)
+	if v, _ := GetBaggage("joined"); v != "1" {
+		t.Errorf("unexpected baggage after Join: got %q; want %q", v, "1")
+	}
+	if r := GetRPCMetadata(); len(r.Events) != 2 || r.Events[0] != childEvent {
+		t.Errorf("unexpected events after Join: got %v; want [%v %v]", r.Events, childEvent, GetEventID())
+	}
+}
+
+func TestUnspawnedGoroutines(t *testing.T) {
+	NewTask()
+	taskID := GetTaskID()
+	done := make(chan struct{})
+	// goroutines not spawned with XGo, such as
+	// those of RPC servers, have their own state
+	go func() {
+		NewTask()
+		SetBaggage("unspawned", "1")
+		close(done)
+	}()
+	<-done
+	if GetTaskID() != taskID {
+		t.Errorf("task changed by unspawned goroutine")
+	}
+	if _, ok := GetBaggage("unspawned"); ok {
+		t.Errorf("baggage set by unspawned goroutine")
+	}
+}
+
+func TestSnapshotRun(t *testing.T) {
+	NewTask()
+	eventID := GetEventID()
+	s := local.Snapshot()
+
+	for i := 0; i < 2; i++ {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			local.Run(s, func() {
+				if GetEventID() != eventID || len(GetTaskTags()) != 0 {
+					t.Errorf("unexpected state in Run: event %v, tags %v; want event %v, no tags", GetEventID(), GetTaskTags(), eventID)
+				}
+				// must not affect the snapshot
+				SetEventID(42)
+				AddTaskTags("leak")
+			})
+		}()
+		<-done
+	}
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/brown-csci1380/tracing-framework-go/local"
+)
+
+// SetRecordPanics sets whether goroutines spawned with t.XGo
+// record panics. If enabled, when such a goroutine panics, t
+// logs an event tagged with ErrorTag under the goroutine's task,
+// recording the panic value and the goroutine's stack trace, and
+// then the goroutine panics again with the same value, so that
+// crashes are visible in the causal graph of the task which caused
+// them. Since the panic is recovered in order to log it, the stack
+// trace printed when the process crashes shows the panic being raised
+// again in the deferred function which logged it; the stack trace of
+// the original panic is the one recorded in the event's "stack" field.
+// The setting takes effect for goroutines spawned after it is changed.
+func (t *Tracer) SetRecordPanics(enabled bool) {
+	t.mtx.Lock()
+	t.recordPanics = enabled
+	t.mtx.Unlock()
+}
+
+// XGo runs f in a new goroutine, but copies the local vars from
+// the current goroutine first. If panics are recorded (see
+// SetRecordPanics), a panic in f is logged through t before
+// it crashes the process.
+func (t *Tracer) XGo(f func()) {
+	t.mtx.RLock()
+	record := t.recordPanics
+	t.mtx.RUnlock()
+	if !record {
+		local.Go(f)
+		return
+	}
+	local.Go(func() {
+		defer t.recordPanic()
+		f()
+	})
+}
+
+// recordPanic must be deferred; if the goroutine is
+// panicking, it logs the panic and then panics again.
+func (t *Tracer) recordPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	t.LogFields(LevelError, fmt.Sprintf("panic: %v", r),
+		Field{"panic", fmt.Sprint(r)}, Field{"stack", string(debug.Stack())})
+	panic(r)
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestRecordPanics(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+	tr.SetRecordPanics(true)
+
+	NewTask()
+	tr.Log("before")
+	done := make(chan struct{})
+	tr.XGo(func() {
+		defer close(done)
+		tr.Log("no panic")
+	})
+	<-done
+	if len(sink.Reports()) != 2 {
+		t.Fatalf("unexpected number of reports: got %v; want 2", len(sink.Reports()))
+	}
+
+	// a goroutine spawned by XGo crashes the process when it
+	// panics again, so recover from it in the goroutine itself
+	recovered := make(chan interface{})
+	XGo(func() {
+		defer func() { recovered <- recover() }()
+		defer tr.recordPanic()
+		panic("boom")
+	})
+	if r := <-recovered; r != "boom" {
+		t.Errorf("unexpected panic value: got %v; want boom", r)
+	}
+
+	if len(sink.Reports()) != 3 {
+		t.Fatalf("unexpected number of reports: got %v; want 3", len(sink.Reports()))
+	}
+	r := sink.Reports()[2]
+	if r.Label != "panic: boom" || r.TaskID != GetTaskID() {
+		t.Errorf("unexpected report: %v", r)
+	}
+	if pe := r.ParentEventIDs; len(pe) != 1 || pe[0] != sink.Reports()[0].EventID {
+		t.Errorf("unexpected parent events: got %v; want [%v]", pe, sink.Reports()[0].EventID)
+	}
+	if tags := r.Tags; len(tags) != 1 || tags[0] != ErrorTag {
+		t.Errorf("unexpected tags: got %v; want [%v]", tags, ErrorTag)
+	}
+	fields := make(map[string]string)
+	for _, f := range r.Fields {
+		fields[f.Key] = f.Value
+	}
+	if fields["panic"] != "boom" {
+		t.Errorf("unexpected panic field: got %q; want boom", fields["panic"])
+	}
+	if !strings.Contains(fields["stack"], "TestRecordPanics") {
+		t.Errorf("unexpected stack field: got %q", fields["stack"])
+	}
+}
+
+// TestRecordPanicsCrash checks that a goroutine spawned by XGo still
+// crashes the process after its panic is recorded. The panicking
+// goroutine runs in a subprocess, which reports the recorded event
+// on stderr before it crashes.
+func TestRecordPanicsCrash(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") == "1" {
+		tr := NewTracer(DefaultServerString)
+		var sink reporttest.Sink
+		tr.AddSink(SinkFunc(func(report []byte) {
+			sink.Report(report)
+			r := sink.Reports()[len(sink.Reports())-1]
+			stack, _ := r.Field("stack")
+			fmt.Fprintf(os.Stderr, "reported %q, stack from panicking function: %v\n",
+				r.Label, strings.Contains(stack, "TestRecordPanicsCrash.func"))
+		}))
+		tr.SetRecordPanics(true)
+
+		NewTask()
+		tr.XGo(func() { panic("boom") })
+		select {}
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRecordPanicsCrash$")
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Fatalf("unexpected error: got %v; want exit error\n%s", err, out)
+	}
+	for _, s := range []string{`reported "panic: boom", stack from panicking function: true`, "panic: boom"} {
+		if !strings.Contains(string(out), s) {
+			t.Errorf("unexpected output: missing %q\n%s", s, out)
+		}
+	}
+}
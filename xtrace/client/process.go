@@ -0,0 +1,118 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Environment variables from which process metadata is read at
+// initialization. Each X-Trace specific variable takes precedence
+// over the corresponding conventional variable (e.g., one set with
+// the Kubernetes downward API), listed after it in parentheses.
+//
+//	XTRACE_HOST           the host recorded in reports (default os.Hostname())
+//	XTRACE_PROCESS_NAME   the default process name (default the executable's name)
+//	XTRACE_CONTAINER_ID   the container ID (CONTAINER_ID)
+//	XTRACE_POD_NAME       the pod name (POD_NAME)
+//	XTRACE_POD_NAMESPACE  the pod namespace (POD_NAMESPACE)
+//
+// The container ID, pod name and pod namespace are recorded in
+// every report as the custom fields "container_id", "pod_name"
+// and "pod_namespace" respectively, if set.
+const (
+	HostEnvVar         = "XTRACE_HOST"
+	ProcessNameEnvVar  = "XTRACE_PROCESS_NAME"
+	ContainerIDEnvVar  = "XTRACE_CONTAINER_ID"
+	PodNameEnvVar      = "XTRACE_POD_NAME"
+	PodNamespaceEnvVar = "XTRACE_POD_NAMESPACE"
+)
+
+// process holds metadata about the current process, resolved
+// once so that it need not be looked up for every report.
+var process = struct {
+	host   string
+	pid    int32
+	name   string
+	fields []Field
+	sync.RWMutex
+}{
+	host:   resolveHost(),
+	pid:    int32(os.Getpid()),
+	name:   resolveProcessName(),
+	fields: resolveProcessFields(),
+}
+
+func resolveHost() string {
+	if host := os.Getenv(HostEnvVar); host != "" {
+		return host
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+func resolveProcessName() string {
+	if name := os.Getenv(ProcessNameEnvVar); name != "" {
+		return name
+	}
+	if len(os.Args) > 0 {
+		return filepath.Base(os.Args[0])
+	}
+	return ""
+}
+
+func resolveProcessFields() []Field {
+	var fields []Field
+	for _, v := range []struct{ key, env, fallback string }{
+		{"container_id", ContainerIDEnvVar, "CONTAINER_ID"},
+		{"pod_name", PodNameEnvVar, "POD_NAME"},
+		{"pod_namespace", PodNamespaceEnvVar, "POD_NAMESPACE"},
+	} {
+		val := os.Getenv(v.env)
+		if val == "" {
+			val = os.Getenv(v.fallback)
+		}
+		if val != "" {
+			fields = append(fields, Field{Key: v.key, Value: val})
+		}
+	}
+	return fields
+}
+
+// SetHost sets the host recorded in reports, overriding
+// the host read from the environment or os.Hostname.
+func SetHost(host string) {
+	process.Lock()
+	process.host = host
+	process.Unlock()
+}
+
+// Host returns the host recorded in reports.
+func Host() string {
+	process.RLock()
+	defer process.RUnlock()
+	return process.host
+}
+
+// SetProcessField sets a custom field recorded in every report
+// logged by this process, such as a container or pod identifier.
+// If value is empty, the field is removed.
+func SetProcessField(key, value string) {
+	process.Lock()
+	defer process.Unlock()
+	fields := make([]Field, 0, len(process.fields)+1)
+	for _, f := range process.fields {
+		if f.Key != key {
+			fields = append(fields, f)
+		}
+	}
+	if value != "" {
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	// replace rather than modify the slice, since
+	// logReport uses it without holding the lock
+	process.fields = fields
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestProcessMetadata(t *testing.T) {
+	defer SetHost(Host())
+	defer SetProcessField("pod_name", "")
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	SetHost("host")
+	SetProcessField("pod_name", "pod")
+	NewTask()
+	tr.Log("foo")
+
+	r := sink.Reports()[0]
+	if r.Host != "host" {
+		t.Errorf("unexpected host: got %q; want %q", r.Host, "host")
+	}
+	if len(r.Fields) != 1 || r.Fields[0] != (reporttest.Field{Key: "pod_name", Value: "pod"}) {
+		t.Errorf("unexpected fields: got %v; want [{pod_name pod}]", r.Fields)
+	}
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A Sampler decides, when a new task is created with NewTask,
+// whether that task should be traced. If it is not, no events
+// are reported for the task by this process or by any process
+// which receives the decision through RPCMetadata.
+type Sampler interface {
+	// Sample returns whether a new task with
+	// the given tags should be traced.
+	Sample(tags []string) bool
+}
+
+// SamplerFunc adapts an ordinary function to the Sampler interface.
+type SamplerFunc func(tags []string) bool
+
+// Sample returns f(tags).
+func (f SamplerFunc) Sample(tags []string) bool {
+	return f(tags)
+}
+
+// AlwaysSample is a Sampler which traces every task.
+// It is the default Sampler.
+var AlwaysSample Sampler = SamplerFunc(func([]string) bool { return true })
+
+// NeverSample is a Sampler which traces no tasks
+// (other than those for which tracing is forced).
+var NeverSample Sampler = SamplerFunc(func([]string) bool { return false })
+
+// ProbabilitySampler returns a Sampler which traces
+// each task independently with probability p.
+func ProbabilitySampler(p float64) Sampler {
+	return SamplerFunc(func([]string) bool {
+		return rand.Float64() < p
+	})
+}
+
+// RateLimitSampler returns a Sampler which traces
+// at most perSecond tasks per second, allowing bursts
+// of up to burst tasks. Tasks beyond the limit are
+// not traced.
+func RateLimitSampler(perSecond float64, burst int) Sampler {
+	return &rateLimitSampler{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+type rateLimitSampler struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+	mtx         sync.Mutex
+}
+
+func (r *rateLimitSampler) Sample([]string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// TagSampler returns a Sampler which applies per-tag rules.
+// The first of a task's tags which has a rule in rules
+// decides whether the task is traced; if none of its tags
+// has a rule, fallback decides.
+func TagSampler(rules map[string]Sampler, fallback Sampler) Sampler {
+	return SamplerFunc(func(tags []string) bool {
+		for _, tag := range tags {
+			if s, ok := rules[tag]; ok {
+				return s.Sample(tags)
+			}
+		}
+		return fallback.Sample(tags)
+	})
+}
+
+var sampler = struct {
+	s Sampler
+	sync.RWMutex
+}{s: AlwaysSample}
+
+// SetSampler sets the Sampler used by NewTask
+// to decide whether new tasks are traced.
+func SetSampler(s Sampler) {
+	sampler.Lock()
+	sampler.s = s
+	sampler.Unlock()
+}
+
+func sample(tags []string) bool {
+	sampler.RLock()
+	s := sampler.s
+	sampler.RUnlock()
+	return s.Sample(tags)
+}
+
+// Sampled returns whether the current goroutine's
+// task is being traced.
+func Sampled() bool {
+	l := getLocal()
+	return l.sampled || l.forced
+}
+
+// Forced returns whether tracing has been forced
+// for the current goroutine's task.
+func Forced() bool {
+	return getLocal().forced
+}
+
+// ForceTrace forces the current goroutine's task
+// to be traced regardless of the sampling decision
+// made when it was created. The decision propagates
+// through RPCMetadata to downstream services, so it
+// can be used to debug a single request.
+func ForceTrace() {
+	getLocal().forced = true
+}
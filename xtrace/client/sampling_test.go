@@ -0,0 +1,64 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestRateLimitSampler(t *testing.T) {
+	s := RateLimitSampler(1, 3)
+	for i := 0; i < 3; i++ {
+		if !s.Sample(nil) {
+			t.Errorf("sample %v within burst not traced", i)
+		}
+	}
+	if s.Sample(nil) {
+		t.Errorf("sample beyond burst traced")
+	}
+}
+
+func TestTagSampler(t *testing.T) {
+	s := TagSampler(map[string]Sampler{"debug": AlwaysSample, "noisy": NeverSample}, NeverSample)
+	cases := []struct {
+		tags   []string
+		expect bool
+	}{
+		{nil, false},
+		{[]string{"debug"}, true},
+		{[]string{"noisy"}, false},
+		{[]string{"other", "debug", "noisy"}, true},
+		{[]string{"noisy", "debug"}, false},
+	}
+	for _, c := range cases {
+		if got := s.Sample(c.tags); got != c.expect {
+			t.Errorf("unexpected result for %v: got %v; want %v", c.tags, got, c.expect)
+		}
+	}
+}
+
+func TestUnsampledTask(t *testing.T) {
+	defer SetSampler(AlwaysSample)
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	SetSampler(NeverSample)
+	NewTask()
+	tr.Log("foo")
+	if len(sink.Reports()) != 0 {
+		t.Errorf("unexpected reports for unsampled task: %v", sink.Reports())
+	}
+	if md := GetRPCMetadata(); !md.Unsampled {
+		t.Errorf("unsampled task propagated as sampled")
+	}
+
+	ForceTrace()
+	tr.Log("bar")
+	if len(sink.Reports()) != 1 {
+		t.Errorf("unexpected number of reports for forced task: got %v; want 1", len(sink.Reports()))
+	}
+	if md := GetRPCMetadata(); !md.Forced {
+		t.Errorf("forced task propagated as not forced")
+	}
+}
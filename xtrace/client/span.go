@@ -0,0 +1,117 @@
+package client
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// A Span is a named interval of work within a task, such as a
+// function call. Starting and ending a Span each log an X-Trace
+// event; the end event records the Span's duration and is linked
+// to the start event, so Spans are part of the task's causal graph.
+//
+// Spans nest: the current goroutine's innermost open Span is stored
+// in goroutine-local storage, and becomes the parent of any Span
+// started in the same goroutine (or in goroutines spawned with XGo)
+// until it ends. A typical use is
+//
+//	defer client.StartSpan("name").End()
+type Span struct {
+	tracer     *Tracer
+	name       string
+	id         int64
+	parent     *Span
+	start      time.Time
+	startEvent int64
+	// ended is set by the first call to End, which may
+	// race with calls from other goroutines
+	ended atomic.Bool
+}
+
+// StartSpan starts a new Span with the given name as a child of the
+// current goroutine's innermost open Span (if any), and makes it the
+// current goroutine's innermost open Span.
+func (t *Tracer) StartSpan(name string) *Span {
+	l := getLocal()
+	s := &Span{
+		tracer: t,
+		name:   name,
+		id:     randInt64(),
+		parent: l.currentSpan(),
+	}
+	keys := []string{"span", "span_name"}
+	values := []string{strconv.FormatInt(s.id, 10), name}
+	if s.parent != nil {
+		keys = append(keys, "parent_span")
+		values = append(values, strconv.FormatInt(s.parent.id, 10))
+	}
+	t.logReport("StartSpan: "+name, PopRedundancies(), nil, keys, values)
+
+	s.start = time.Now()
+	s.startEvent = GetEventID()
+	l.span = s
+	return s
+}
+
+// StartSpan calls DefaultTracer.StartSpan(name).
+func StartSpan(name string) *Span {
+	return DefaultTracer.StartSpan(name)
+}
+
+// CurrentSpan returns the current goroutine's
+// innermost open Span, or nil if there is none.
+func CurrentSpan() *Span {
+	return getLocal().currentSpan()
+}
+
+// currentSpan returns l's innermost open Span, skipping over
+// any Spans which have been ended (possibly by other goroutines).
+func (l *localStorage) currentSpan() *Span {
+	for l.span != nil && l.span.ended.Load() {
+		l.span = l.span.parent
+	}
+	return l.span
+}
+
+// Name returns the Span's name.
+func (s *Span) Name() string {
+	return s.name
+}
+
+// ID returns the Span's ID, which is recorded in
+// the "span" field of its start and end events.
+func (s *Span) ID() int64 {
+	return s.id
+}
+
+// Parent returns the Span's parent, or nil if it has none.
+func (s *Span) Parent() *Span {
+	return s.parent
+}
+
+// End ends the Span, logging an event which records its duration.
+// Once s has ended, its nearest open ancestor becomes the innermost
+// open Span of any goroutine whose innermost open Span was s, even
+// if End is called from a different goroutine. Calling End more than
+// once has no effect, even if the calls are made from different
+// goroutines.
+func (s *Span) End() {
+	if !s.ended.CompareAndSwap(false, true) {
+		return
+	}
+	d := time.Since(s.start)
+
+	preceding := PopRedundancies()
+	if s.startEvent != GetEventID() {
+		preceding = append(preceding, s.startEvent)
+	}
+	s.tracer.logReport("EndSpan: "+s.name, preceding, nil,
+		[]string{"span", "span_name", "start_event", "duration"},
+		[]string{strconv.FormatInt(s.id, 10), s.name, strconv.FormatInt(s.startEvent, 10), strconv.FormatInt(int64(d), 10)})
+
+	// if s was the current goroutine's innermost open Span, its
+	// nearest open ancestor now is; if s was started by another
+	// goroutine, that goroutine skips s when it next uses its Spans
+	getLocal().currentSpan()
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestSpan(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	NewTask()
+	func() {
+		defer tr.StartSpan("outer").End()
+		inner := tr.StartSpan("inner")
+		if CurrentSpan() != inner || inner.Parent() == nil || inner.Parent().Name() != "outer" {
+			t.Errorf("unexpected span nesting")
+		}
+		tr.Log("foo")
+		inner.End()
+	}()
+	if CurrentSpan() != nil {
+		t.Errorf("span still open after End: %v", CurrentSpan().Name())
+	}
+
+	labels := []string{"StartSpan: outer", "StartSpan: inner", "foo", "EndSpan: inner", "EndSpan: outer"}
+	if len(sink.Reports()) != len(labels) {
+		t.Fatalf("unexpected number of reports: got %v; want %v", len(sink.Reports()), len(labels))
+	}
+	for i, l := range labels {
+		if sink.Reports()[i].Label != l {
+			t.Errorf("unexpected label for report %v: got %q; want %q", i, sink.Reports()[i].Label, l)
+		}
+	}
+	end := sink.Reports()[3]
+	start := sink.Reports()[1].EventID
+	if pe := end.ParentEventIDs; len(pe) != 2 || pe[0] != start {
+		t.Errorf("end event not linked to start event %v: parents %v", start, pe)
+	}
+}
+
+func TestSpanEndedElsewhere(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+
+	NewTask()
+	s := tr.StartSpan("handoff")
+	done := make(chan struct{})
+	go func() {
+		s.End()
+		close(done)
+	}()
+	<-done
+	if CurrentSpan() != nil {
+		t.Errorf("span ended by another goroutine still current: %v", CurrentSpan().Name())
+	}
+	if next := tr.StartSpan("next"); next.Parent() != nil {
+		t.Errorf("unexpected parent of span: got %v; want none", next.Parent().Name())
+	}
+
+	// a new task does not inherit the previous task's spans
+	NewTask()
+	if CurrentSpan() != nil {
+		t.Errorf("span of previous task current in new task: %v", CurrentSpan().Name())
+	}
+}
+
+func TestSpanEndConcurrent(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	NewTask()
+	s := tr.StartSpan("span")
+	done := make(chan struct{})
+	tr.XGo(func() {
+		s.End()
+		close(done)
+	})
+	s.End()
+	<-done
+
+	ends := 0
+	for _, r := range sink.Reports() {
+		if r.Label == "EndSpan: span" {
+			ends++
+		}
+	}
+	if ends != 1 {
+		t.Errorf("unexpected number of end events: got %v; want 1", ends)
+	}
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"log"
+)
+
+// LogWriter returns an io.Writer suitable for passing to the SetOutput
+// method of logger (or to log.SetOutput if logger is the standard
+// logger). Each line logger writes is written unmodified to every
+// writer in wrapped, and is logged through t as an event at Level l
+// with logger's prefix and header (date, time and file, as configured
+// by logger's flags) and trailing newline removed. If logger is nil,
+// only the trailing newline is removed. Per-package minimum Levels
+// are not applied to these events (see stdLogWriter).
+func (t *Tracer) LogWriter(logger *log.Logger, l Level, wrapped ...io.Writer) io.Writer {
+	return io.MultiWriter(append(wrapped, stdLogWriter{t: t, logger: logger, level: l})...)
+}
+
+// LogWriter calls DefaultTracer.LogWriter(logger, l, wrapped...).
+func LogWriter(logger *log.Logger, l Level, wrapped ...io.Writer) io.Writer {
+	return DefaultTracer.LogWriter(logger, l, wrapped...)
+}
+
+// NewLogger returns a new *log.Logger whose output is logged
+// through t as events at Level l, if l is at least the global
+// minimum Level. Per-package minimum Levels are not applied.
+func (t *Tracer) NewLogger(l Level) *log.Logger {
+	return log.New(stdLogWriter{t: t, level: l}, "", 0)
+}
+
+// NewLogger calls DefaultTracer.NewLogger(l).
+func NewLogger(l Level) *log.Logger {
+	return DefaultTracer.NewLogger(l)
+}
+
+// stdLogWriter logs each line written by a log.Logger. A log.Logger
+// does not record the program counter of its caller, so a line is
+// logged if its Level is at least the global minimum Level, even if
+// the calling package has a different minimum Level.
+type stdLogWriter struct {
+	t      *Tracer
+	logger *log.Logger
+	level  Level
+}
+
+func (w stdLogWriter) Write(p []byte) (n int, err error) {
+	if !EnabledAt(0, w.level) {
+		return len(p), nil
+	}
+	msg := bytes.TrimSuffix(p, []byte("\n"))
+	if w.logger != nil {
+		msg = stripLogHeader(msg, w.logger.Prefix(), w.logger.Flags())
+	}
+	w.t.LogFields(w.level, string(msg))
+	return len(p), nil
+}
+
+// stripLogHeader removes the prefix and header that
+// a log.Logger with the given prefix and flags adds
+// to each line it writes (see log.Logger.Output).
+func stripLogHeader(line []byte, prefix string, flags int) []byte {
+	if flags&log.Lmsgprefix == 0 {
+		line = bytes.TrimPrefix(line, []byte(prefix))
+	}
+	if flags&log.Ldate != 0 {
+		// 2009/01/23
+		line = skip(line, len("2009/01/23 "))
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		// 01:23:23 or 01:23:23.123123
+		n := len("01:23:23 ")
+		if flags&log.Lmicroseconds != 0 {
+			n += len(".123123")
+		}
+		line = skip(line, n)
+	}
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		// file.go:23:
+		if i := bytes.Index(line, []byte(": ")); i >= 0 {
+			line = line[i+2:]
+		}
+	}
+	if flags&log.Lmsgprefix != 0 {
+		line = bytes.TrimPrefix(line, []byte(prefix))
+	}
+	return line
+}
+
+func skip(b []byte, n int) []byte {
+	if n > len(b) {
+		return b[len(b):]
+	}
+	return b[n:]
+}
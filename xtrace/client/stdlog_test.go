@@ -0,0 +1,25 @@
+package client
+
+import (
+	"log"
+	"testing"
+)
+
+func TestStripLogHeader(t *testing.T) {
+	cases := []struct {
+		prefix string
+		flags  int
+		line   string
+	}{
+		{"", 0, "msg"},
+		{"pfx: ", 0, "pfx: msg"},
+		{"", log.LstdFlags, "2009/01/23 01:23:23 msg"},
+		{"pfx: ", log.LstdFlags | log.Lmicroseconds, "pfx: 2009/01/23 01:23:23.123123 msg"},
+		{"pfx: ", log.Ltime | log.Lshortfile | log.Lmsgprefix, "01:23:23 file.go:23: pfx: msg"},
+	}
+	for _, c := range cases {
+		if got := string(stripLogHeader([]byte(c.line), c.prefix, c.flags)); got != "msg" {
+			t.Errorf("unexpected result for %q: got %q; want %q", c.line, got, "msg")
+		}
+	}
+}
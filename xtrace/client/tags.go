@@ -0,0 +1,50 @@
+package client
+
+// AddEventTags adds tags to the next event logged by the
+// current goroutine. They are not attached to any later events.
+func AddEventTags(tags ...string) {
+	l := getLocal()
+	if l.tags == nil {
+		l.tags = tags
+	} else {
+		l.tags = append(l.tags, tags...)
+	}
+}
+
+// AddTaskTags adds sticky tags to the current goroutine's task.
+// Unlike event tags, task tags are attached to every event logged
+// for the task from then on, and propagate with the task's other
+// X-Trace state to spawned goroutines (see XGo) and, through
+// RPCMetadata, to other services, so that the whole task can be
+// indexed by them regardless of where its events were logged.
+func AddTaskTags(tags ...string) {
+	l := getLocal()
+	l.taskTags = unionTags(l.taskTags, tags)
+}
+
+// GetTaskTags returns the current goroutine's task tags.
+// The returned slice must not be modified.
+func GetTaskTags() []string {
+	return getLocal().taskTags
+}
+
+// unionTags returns a new slice containing the tags in a followed
+// by any tags in b which are not in a. Since the slices of task tags
+// are shared between goroutines, they are never modified in place.
+func unionTags(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	union := make([]string, len(a), len(a)+len(b))
+	copy(union, a)
+outer:
+	for _, tag := range b {
+		for _, t := range union {
+			if t == tag {
+				continue outer
+			}
+		}
+		union = append(union, tag)
+	}
+	return union
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestTaskTags(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	NewTask("a")
+	AddTaskTags("b")
+	AddEventTags("c")
+	tr.Log("foo")
+	tr.Log("bar")
+
+	expect := [][]string{{"a", "b", "c"}, {"a", "b"}}
+	for i, tags := range expect {
+		got := sink.Reports()[i].Tags
+		if len(got) != len(tags) {
+			t.Errorf("unexpected tags for report %v: got %v; want %v", i, got, tags)
+			continue
+		}
+		for j := range tags {
+			if got[j] != tags[j] {
+				t.Errorf("unexpected tags for report %v: got %v; want %v", i, got, tags)
+				break
+			}
+		}
+	}
+	if md := GetRPCMetadata(); len(md.Tags) != 2 {
+		t.Errorf("unexpected tags in RPC metadata: got %v; want [a b]", md.Tags)
+	}
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Default limits used by a TailPolicy whose
+// corresponding fields are zero.
+const (
+	DefaultTailMaxTasks = 1024
+	DefaultTailMaxBytes = 16 << 20
+)
+
+// A TailPolicy configures tail-based retention for a Tracer.
+// When a Tracer has a TailPolicy, reports are not sent as
+// they are logged; instead, they are buffered per task, and
+// a task's reports are only sent once the task is found to
+// be interesting. From then on, the task's reports are sent
+// immediately. Tasks which are never found interesting are
+// eventually evicted from the buffer and their reports dropped.
+//
+// A task is interesting if any of its events carries one
+// of ErrorTags or Tags, or if an event is logged more than
+// Latency after the task was started in this process (by
+// NewTask, RPCReceived or SetTaskID). Once a task is
+// interesting, it stays so until EndTask or EndSubtask is
+// called, even if other tasks are evicted from the buffer;
+// up to MaxTasks interesting tasks are remembered, in
+// addition to the MaxTasks tasks whose reports are buffered.
+type TailPolicy struct {
+	// ErrorTags are the tags which mark an event as an error.
+	// If ErrorTags is nil, it defaults to []string{ErrorTag}.
+	ErrorTags []string
+	// Tags are additional tags which make a task interesting.
+	Tags []string
+	// Latency is the latency threshold beyond which a task
+	// is interesting. If it is zero, latency is not considered.
+	Latency time.Duration
+
+	// MaxTasks is the maximum number of tasks tracked at
+	// once. If it is zero, DefaultTailMaxTasks is used.
+	MaxTasks int
+	// MaxBytes is the maximum number of bytes of reports
+	// buffered at once. If it is zero, DefaultTailMaxBytes
+	// is used.
+	MaxBytes int
+}
+
+// SetTailPolicy enables tail-based retention for t using the given
+// policy. If p is nil, tail-based retention is disabled, and any
+// reports still buffered are dropped.
+func (t *Tracer) SetTailPolicy(p *TailPolicy) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if p == nil {
+		t.tail = nil
+		return
+	}
+	t.tail = newTailBuffer(*p)
+}
+
+// SetTailPolicy calls DefaultTracer.SetTailPolicy(p).
+func SetTailPolicy(p *TailPolicy) {
+	DefaultTracer.SetTailPolicy(p)
+}
+
+type tailTask struct {
+	id      int64
+	reports [][]byte
+	size    int
+	elem    *list.Element
+}
+
+type tailBuffer struct {
+	policy TailPolicy
+	tags   map[string]bool
+	// tasks whose reports are buffered
+	tasks map[int64]*tailTask
+	// tasks ordered by when they were first seen,
+	// oldest first; used for eviction
+	order *list.List
+	size  int
+	// retained holds the elements of retainedOrder for the
+	// tasks which have been found interesting; it is kept
+	// separately from tasks so that evicting buffered
+	// reports does not affect them
+	retained      map[int64]*list.Element
+	retainedOrder *list.List
+	mtx           sync.Mutex
+}
+
+func newTailBuffer(p TailPolicy) *tailBuffer {
+	if p.ErrorTags == nil {
+		p.ErrorTags = []string{ErrorTag}
+	}
+	if p.MaxTasks <= 0 {
+		p.MaxTasks = DefaultTailMaxTasks
+	}
+	if p.MaxBytes <= 0 {
+		p.MaxBytes = DefaultTailMaxBytes
+	}
+	b := &tailBuffer{
+		policy:        p,
+		tags:          make(map[string]bool),
+		tasks:         make(map[int64]*tailTask),
+		order:         list.New(),
+		retained:      make(map[int64]*list.Element),
+		retainedOrder: list.New(),
+	}
+	for _, tag := range p.ErrorTags {
+		b.tags[tag] = true
+	}
+	for _, tag := range p.Tags {
+		b.tags[tag] = true
+	}
+	return b
+}
+
+// add records a report for the given task, which was started
+// at the given time, and returns the reports which should be
+// sent as a result (which may be none, or all of the task's
+// buffered reports).
+func (b *tailBuffer) add(taskID int64, start time.Time, tags []string, report []byte) [][]byte {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if _, ok := b.retained[taskID]; ok {
+		return [][]byte{report}
+	}
+
+	interesting := b.policy.Latency > 0 && time.Since(start) > b.policy.Latency
+	for _, tag := range tags {
+		if b.tags[tag] {
+			interesting = true
+			break
+		}
+	}
+
+	task, ok := b.tasks[taskID]
+	if interesting {
+		reports := [][]byte{report}
+		if ok {
+			reports = append(task.reports, report)
+			b.remove(task)
+		}
+		b.retain(taskID)
+		return reports
+	}
+
+	if !ok {
+		task = &tailTask{id: taskID}
+		task.elem = b.order.PushBack(task)
+		b.tasks[taskID] = task
+	}
+	task.reports = append(task.reports, report)
+	task.size += len(report)
+	b.size += len(report)
+	b.evict()
+	return nil
+}
+
+// retain marks the given task as interesting, forgetting
+// the oldest interesting task if there are too many.
+func (b *tailBuffer) retain(taskID int64) {
+	b.retained[taskID] = b.retainedOrder.PushBack(taskID)
+	if b.retainedOrder.Len() > b.policy.MaxTasks {
+		delete(b.retained, b.retainedOrder.Remove(b.retainedOrder.Front()).(int64))
+	}
+}
+
+// evict drops the oldest tasks until the
+// buffer is within its limits.
+func (b *tailBuffer) evict() {
+	for b.order.Len() > 0 && (len(b.tasks) > b.policy.MaxTasks || b.size > b.policy.MaxBytes) {
+		b.remove(b.order.Front().Value.(*tailTask))
+	}
+}
+
+// discard drops any state about the given task.
+func (b *tailBuffer) discard(taskID int64) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if task, ok := b.tasks[taskID]; ok {
+		b.remove(task)
+	}
+	if elem, ok := b.retained[taskID]; ok {
+		b.retainedOrder.Remove(elem)
+		delete(b.retained, taskID)
+	}
+}
+
+func (b *tailBuffer) remove(task *tailTask) {
+	b.order.Remove(task.elem)
+	delete(b.tasks, task.id)
+	b.size -= task.size
+}
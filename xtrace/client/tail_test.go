@@ -0,0 +1,121 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestTailRetention(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+	tr.SetTailPolicy(&TailPolicy{Tags: []string{"slow"}})
+
+	NewTask()
+	tr.Log("boring")
+	tr.Log("boring")
+	if len(sink.Reports()) != 0 {
+		t.Fatalf("uninteresting task reported: %v", sink.Reports())
+	}
+
+	NewTask()
+	tr.Log("foo")
+	AddTags("error")
+	tr.Log("bar")
+	if len(sink.Reports()) != 2 {
+		t.Fatalf("unexpected number of reports for error task: got %v; want 2", len(sink.Reports()))
+	}
+	tr.Log("baz")
+	if len(sink.Reports()) != 3 {
+		t.Fatalf("report for retained task not sent immediately")
+	}
+
+	NewTask()
+	AddTags("slow")
+	tr.Log("foo")
+	if len(sink.Reports()) != 4 {
+		t.Fatalf("report for task matching tag rule not sent")
+	}
+}
+
+func TestTailLatency(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+	tr.SetTailPolicy(&TailPolicy{Latency: 10 * time.Millisecond})
+
+	NewTask()
+	tr.Log("foo")
+	time.Sleep(20 * time.Millisecond)
+	tr.Log("bar")
+	if len(sink.Reports()) != 2 {
+		t.Fatalf("unexpected number of reports for slow task: got %v; want 2", len(sink.Reports()))
+	}
+}
+
+func TestTailLatencyFromStart(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+	tr.SetTailPolicy(&TailPolicy{Latency: 10 * time.Millisecond})
+
+	// latency is measured from the start of the
+	// task, not from its first buffered event
+	NewTask()
+	time.Sleep(20 * time.Millisecond)
+	tr.Log("foo")
+	if len(sink.Reports()) != 1 {
+		t.Fatalf("unexpected number of reports for slow task: got %v; want 1", len(sink.Reports()))
+	}
+
+	// a task received with SetTaskID starts when it is set
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SetTaskID(randInt64())
+		time.Sleep(20 * time.Millisecond)
+		tr.Log("bar")
+	}()
+	<-done
+	if len(sink.Reports()) != 2 {
+		t.Fatalf("unexpected number of reports for slow task set with SetTaskID: got %v; want 2", len(sink.Reports()))
+	}
+}
+
+func TestTailEviction(t *testing.T) {
+	b := newTailBuffer(TailPolicy{MaxTasks: 2, MaxBytes: 10})
+	now := time.Now()
+	b.add(1, now, nil, make([]byte, 4))
+	b.add(2, now, nil, make([]byte, 4))
+	b.add(3, now, nil, make([]byte, 4))
+	if len(b.tasks) != 2 || b.tasks[1] != nil {
+		t.Errorf("oldest task not evicted beyond MaxTasks")
+	}
+	b.add(3, now, nil, make([]byte, 4))
+	if b.size > 10 || b.tasks[2] != nil {
+		t.Errorf("oldest task not evicted beyond MaxBytes: size %v", b.size)
+	}
+	if got := b.add(3, now, []string{"error"}, make([]byte, 4)); len(got) != 3 {
+		t.Errorf("unexpected number of flushed reports: got %v; want 3", len(got))
+	}
+}
+
+func TestTailRetainedAfterEviction(t *testing.T) {
+	b := newTailBuffer(TailPolicy{MaxTasks: 2})
+	now := time.Now()
+	if got := b.add(1, now, []string{"error"}, []byte{1}); len(got) != 1 {
+		t.Fatalf("unexpected number of flushed reports: got %v; want 1", len(got))
+	}
+	for id := int64(2); id < 10; id++ {
+		b.add(id, now, nil, []byte{1})
+	}
+	if got := b.add(1, now, nil, []byte{1}); len(got) != 1 {
+		t.Errorf("report for retained task buffered after eviction of other tasks")
+	}
+	b.discard(1)
+	if got := b.add(1, now, nil, []byte{1}); len(got) != 0 {
+		t.Errorf("report for discarded task sent: %v", got)
+	}
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorTag is the tag which marks an event as an error.
+const ErrorTag = "error"
+
+// A Status describes how a task ended.
+type Status int
+
+const (
+	StatusOK Status = iota
+	StatusError
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusError:
+		return "error"
+	default:
+		return "Status(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// taskState is the state of a task in this process.
+// It is shared by all goroutines working on the task,
+// so that the task's events can be summarized when
+// it ends.
+type taskState struct {
+	start   time.Time
+	events  int64 // accessed atomically
+	errored int32 // accessed atomically
+}
+
+func newTaskState() *taskState {
+	return &taskState{start: time.Now()}
+}
+
+// record updates t to account for an
+// event logged with the given tags.
+func (t *taskState) record(tags []string) {
+	if t == nil {
+		return
+	}
+	atomic.AddInt64(&t.events, 1)
+	for _, tag := range tags {
+		if tag == ErrorTag {
+			atomic.StoreInt32(&t.errored, 1)
+		}
+	}
+}
+
+// SetTaskSummaries sets whether t logs a task summary report,
+// recording the number of events logged, the duration, and whether
+// any event was an error, whenever EndTask or EndSubtask is called.
+func (t *Tracer) SetTaskSummaries(enabled bool) {
+	t.mtx.Lock()
+	t.summaries = enabled
+	t.mtx.Unlock()
+}
+
+// EndTask marks the current goroutine's task as finished. It logs
+// a terminal event with the given status and then resets the
+// goroutine's X-Trace state, so that no further events are logged
+// until a new task is started or received. EndTask should be called
+// by the process which started the task with NewTask; other processes
+// should use EndSubtask.
+func (t *Tracer) EndTask(status Status) {
+	t.endTask("EndTask", "task", status)
+}
+
+// EndSubtask is like EndTask, but only marks the current process's
+// part of the task (e.g., an RPC handler) as finished.
+func (t *Tracer) EndSubtask(status Status) {
+	t.endTask("EndSubtask", "subtask", status)
+}
+
+// EndSubtaskRPC is like EndSubtask, but returns the goroutine's
+// RPCMetadata as of the subtask's last event, before it is reset.
+// An RPC server which returns it to the caller links the caller's
+// next event to the end of the subtask.
+func (t *Tracer) EndSubtaskRPC(status Status) RPCMetadata {
+	return t.endTask("EndSubtask", "subtask", status)
+}
+
+// endTask ends the current goroutine's task or subtask, and
+// returns its RPCMetadata as of the last event logged.
+func (t *Tracer) endTask(label, scope string, status Status) RPCMetadata {
+	l := getLocal()
+	var tags []string
+	if status == StatusError {
+		tags = []string{ErrorTag}
+	}
+	t.logReport(label, PopRedundancies(), tags,
+		[]string{"scope", "status"}, []string{scope, status.String()})
+
+	t.mtx.RLock()
+	summaries, tail := t.summaries, t.tail
+	t.mtx.RUnlock()
+	if summaries && l.task != nil {
+		errored := atomic.LoadInt32(&l.task.errored) == 1
+		t.logReport("TaskSummary", nil, nil,
+			[]string{"scope", "events", "duration", "error"},
+			[]string{scope, strconv.FormatInt(atomic.LoadInt64(&l.task.events), 10),
+				strconv.FormatInt(int64(time.Since(l.task.start)), 10), strconv.FormatBool(errored)})
+	}
+	if tail != nil {
+		tail.discard(l.taskID)
+	}
+
+	r := GetRPCMetadata()
+	*l = localStorage{redundancies: []int64{}, sampled: true}
+	return r
+}
+
+// EndTask calls DefaultTracer.EndTask(status).
+func EndTask(status Status) {
+	DefaultTracer.EndTask(status)
+}
+
+// EndSubtask calls DefaultTracer.EndSubtask(status).
+func EndSubtask(status Status) {
+	DefaultTracer.EndSubtask(status)
+}
+
+// EndSubtaskRPC calls DefaultTracer.EndSubtaskRPC(status).
+func EndSubtaskRPC(status Status) RPCMetadata {
+	return DefaultTracer.EndSubtaskRPC(status)
+}
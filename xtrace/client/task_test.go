@@ -0,0 +1,65 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestEndTask(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	tr.SetTaskSummaries(true)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	NewTask()
+	taskID := GetTaskID()
+	tr.Log("foo")
+	AddTags(ErrorTag)
+	tr.Log("bar")
+	tr.EndTask(StatusOK)
+
+	if len(sink.Reports()) != 4 {
+		t.Fatalf("unexpected number of reports: got %v; want 4", len(sink.Reports()))
+	}
+	end, summary := sink.Reports()[2], sink.Reports()[3]
+	if end.Label != "EndTask" || end.TaskID != taskID {
+		t.Errorf("unexpected terminal event: %v", end)
+	}
+	fields := make(map[string]string)
+	for _, f := range summary.Fields {
+		fields[f.Key] = f.Value
+	}
+	if fields["events"] != "3" || fields["error"] != "true" {
+		t.Errorf("unexpected summary fields: %v", fields)
+	}
+
+	if GetTaskID() != 0 {
+		t.Errorf("task ID not reset by EndTask: got %v", GetTaskID())
+	}
+	tr.Log("baz")
+	if len(sink.Reports()) != 4 {
+		t.Errorf("event logged after EndTask")
+	}
+}
+
+func TestEndSubtaskRPC(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	NewTask()
+	taskID := GetTaskID()
+	r := tr.EndSubtaskRPC(StatusOK)
+	if len(sink.Reports()) != 1 {
+		t.Fatalf("unexpected number of reports: got %v; want 1", len(sink.Reports()))
+	}
+	// the metadata links the caller to the terminal event
+	end := sink.Reports()[0]
+	if r.TaskID != taskID || len(r.Events) != 1 || r.Events[0] != end.EventID {
+		t.Errorf("unexpected metadata: got task %v, events %v; want task %v, events [%v]", r.TaskID, r.Events, taskID, end.EventID)
+	}
+	if GetTaskID() != 0 {
+		t.Errorf("task ID not reset by EndSubtaskRPC: got %v", GetTaskID())
+	}
+}
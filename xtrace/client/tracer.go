@@ -0,0 +1,287 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client/internal"
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/pubsub"
+	"github.com/golang/protobuf/proto"
+)
+
+// A Sink receives every report logged through a Tracer,
+// in addition to the X-Trace server the Tracer is
+// connected to (if any). report is a serialized
+// XTraceReportv4 protocol buffer message.
+type Sink interface {
+	Report(report []byte)
+}
+
+// SinkFunc adapts an ordinary function to the Sink interface.
+type SinkFunc func(report []byte)
+
+// Report calls f(report).
+func (f SinkFunc) Report(report []byte) {
+	f(report)
+}
+
+// A Tracer reports X-Trace events to an X-Trace server
+// and to any number of additional Sinks. The task and
+// event IDs that events are logged under are stored in
+// goroutine-local storage and are shared by all Tracers.
+//
+// A Tracer may be connected, disconnected and reconnected
+// any number of times. It is safe to use a Tracer from
+// multiple goroutines simultaneously.
+type Tracer struct {
+	server       string
+	processName  string
+	client       *pubsub.Client
+	sinks        []Sink
+	tail         *tailBuffer
+	summaries    bool
+	recordPanics bool
+	agent        string
+	mtx          sync.RWMutex
+}
+
+// NewTracer creates a new Tracer which reports to the
+// X-Trace server at the given address once connected.
+// Until Connect is called, events are only reported
+// to the Tracer's Sinks.
+func NewTracer(server string) *Tracer {
+	return &Tracer{
+		server:      server,
+		processName: process.name,
+	}
+}
+
+// Connect initializes a connection to the Tracer's
+// X-Trace server. If the Tracer is already connected,
+// Connect is a no-op.
+func (t *Tracer) Connect() error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.client != nil {
+		return nil
+	}
+	c, err := pubsub.NewClient(t.server)
+	if err != nil {
+		return err
+	}
+	t.client = c
+	return nil
+}
+
+// Disconnect removes the existing connection to the
+// X-Trace server, if any. The Tracer may be reconnected
+// later by calling Connect.
+func (t *Tracer) Disconnect() {
+	t.mtx.Lock()
+	c := t.client
+	t.client = nil
+	t.mtx.Unlock()
+	if c != nil {
+		// reports being published to c are dropped
+		c.Close()
+	}
+}
+
+// Connected returns whether the Tracer is currently
+// connected to its X-Trace server.
+func (t *Tracer) Connected() bool {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	return t.client != nil
+}
+
+// Server returns the address of the Tracer's X-Trace server.
+func (t *Tracer) Server() string {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	return t.server
+}
+
+// SetServer sets the address of the Tracer's X-Trace
+// server. It takes effect the next time Connect is called.
+func (t *Tracer) SetServer(server string) {
+	t.mtx.Lock()
+	t.server = server
+	t.mtx.Unlock()
+}
+
+// ProcessName returns the process name recorded
+// in the Tracer's reports.
+func (t *Tracer) ProcessName() string {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	return t.processName
+}
+
+// SetProcessName sets the process name recorded
+// in the Tracer's reports. It defaults to the
+// value of the XTRACE_PROCESS_NAME environment
+// variable, or the executable's name if it is unset.
+func (t *Tracer) SetProcessName(pname string) {
+	t.mtx.Lock()
+	t.processName = pname
+	t.mtx.Unlock()
+}
+
+// AddSink adds s to the Sinks that every
+// report logged through t is sent to.
+func (t *Tracer) AddSink(s Sink) {
+	t.mtx.Lock()
+	t.sinks = append(t.sinks, s)
+	t.mtx.Unlock()
+}
+
+// LogRedundancies logs a given message with the extra preceding events
+// given. It adds a ParentEventId for all precedingEvents _in addition_
+// to the recorded parent of this event.
+func (t *Tracer) LogRedundancies(str string, precedingEvents []int64) {
+	t.logReport(str, precedingEvents, nil, nil, nil)
+}
+
+// logReport logs a report with the given label and preceding events.
+// tags are added to any tags set on the current goroutine, and keys
+// and values are recorded as custom fields.
+func (t *Tracer) logReport(str string, precedingEvents []int64, tags, keys, values []string) {
+	// the lock is not held while the report is delivered,
+	// so that Sinks may call t's methods, and so that a
+	// slow server does not block t's setters
+	t.mtx.RLock()
+	client, sinks, tail := t.client, t.sinks, t.tail
+	processName, tracerAgent := t.processName, t.agent
+	t.mtx.RUnlock()
+	if client == nil && len(sinks) == 0 {
+		//fail silently
+		return
+	}
+	if !Sampled() {
+		return
+	}
+
+	parent, event := newEvent()
+	var report internal.XTraceReportv4
+
+	report.TaskId = new(int64)
+	*report.TaskId = GetTaskID()
+	if GetTaskID() <= 0 {
+		return
+	}
+	report.ParentEventId = append(precedingEvents, parent)
+	report.EventId = new(int64)
+	*report.EventId = event
+	report.Label = new(string)
+	*report.Label = str
+
+	report.Timestamp = new(int64)
+	*report.Timestamp = time.Now().UnixNano() / 1000 // milliseconds
+
+	process.RLock()
+	host, pid, pfields := process.host, process.pid, process.fields
+	process.RUnlock()
+
+	report.ProcessId = new(int32)
+	*report.ProcessId = pid
+	report.ProcessName = new(string)
+	*report.ProcessName = processName
+	if host != "" {
+		report.Host = new(string)
+		*report.Host = host
+	}
+
+	// report.ThreadName = new(string)
+	// *report.ThreadName = "Thread name"
+	agent := packageAgent()
+	if agent == "" {
+		agent = tracerAgent
+	}
+	if agent != "" {
+		report.Agent = new(string)
+		*report.Agent = agent
+	}
+
+	if class := getLocal().tenantClass; class != 0 {
+		report.TenantClass = new(int32)
+		*report.TenantClass = class
+	}
+
+	report.Tags = unionTags(getLocal().taskTags, getLocal().tags)
+	getLocal().tags = nil
+	report.Tags = unionTags(report.Tags, tags)
+	report.Key = keys[:len(keys):len(keys)]
+	report.Value = values[:len(values):len(values)]
+	for _, f := range pfields {
+		report.Key = append(report.Key, f.Key)
+		report.Value = append(report.Value, f.Value)
+	}
+	getLocal().task.record(report.Tags)
+
+	buf, err := proto.Marshal(&report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "internal error: %v", err)
+	}
+
+	if tail == nil {
+		t.publish(client, sinks, buf)
+		return
+	}
+	start := time.Now()
+	if task := getLocal().task; task != nil {
+		start = task.start
+	}
+	for _, buf := range tail.add(report.GetTaskId(), start, report.Tags, buf) {
+		t.publish(client, sinks, buf)
+	}
+}
+
+// publish sends report to the given Sinks and
+// X-Trace server client, if it is non-nil.
+func (t *Tracer) publish(client *pubsub.Client, sinks []Sink, report []byte) {
+	if client != nil {
+		// NOTE(joshlf): Currently, Log blocks until the log message
+		// has been written to the TCP connection to the X-Trace server.
+		// This makes testing easier, but ideally we should optimize
+		// so that the program can block before it quits, but each
+		// call to Log is not blocking.
+		//
+		// client may be closed by Disconnect at any time, in which
+		// case the report is dropped rather than blocking forever.
+		client.TryPublishBlock(topic, report)
+	}
+
+	for _, s := range sinks {
+		s.Report(report)
+	}
+}
+
+// Log logs the given message.
+func (t *Tracer) Log(str string) {
+	t.LogRedundancies(str, PopRedundancies())
+}
+
+// Logf logs the message produced by formatting
+// args according to format (as with fmt.Sprintf).
+func (t *Tracer) Logf(format string, args ...interface{}) {
+	t.Log(fmt.Sprintf(format, args...))
+}
+
+// MakeWriter returns an io.Writer which writes to
+// every writer in wrapped and logs each write through t.
+func (t *Tracer) MakeWriter(wrapped ...io.Writer) io.Writer {
+	return io.MultiWriter(append(wrapped, xtraceWriter{t})...)
+}
+
+type xtraceWriter struct {
+	t *Tracer
+}
+
+func (x xtraceWriter) Write(p []byte) (n int, err error) {
+	x.t.Log(string(p))
+	return len(p), nil
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestTracerSink(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	tr.SetProcessName("test")
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	NewTask()
+	tr.Log("foo")
+	tr.Logf("bar %v", 1)
+
+	if len(sink.Reports()) != 2 {
+		t.Fatalf("unexpected number of reports: got %v; want 2", len(sink.Reports()))
+	}
+	r := sink.Reports()[1]
+	if r.Label != "bar 1" || r.ProcessName != "test" || r.TaskID != GetTaskID() {
+		t.Errorf("unexpected report: %v", r)
+	}
+	if pe := r.ParentEventIDs; len(pe) != 1 || pe[0] != sink.Reports()[0].EventID {
+		t.Errorf("unexpected parent events: got %v; want [%v]", pe, sink.Reports()[0].EventID)
+	}
+}
+
+func TestTracerReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	tr := NewTracer(l.Addr().String())
+	for i := 0; i < 3; i++ {
+		if err := tr.Connect(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !tr.Connected() {
+			t.Fatalf("tracer not connected after Connect")
+		}
+		tr.Disconnect()
+		if tr.Connected() {
+			t.Fatalf("tracer connected after Disconnect")
+		}
+	}
+}
+
+func TestDisconnectServerDown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tr := NewTracer(l.Addr().String())
+	if err := tr.Connect(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the client cannot reconnect once the server is gone,
+	// so reports block until the Tracer is disconnected
+	l.Close()
+	conn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewTask()
+		for tr.Connected() {
+			tr.Log("event")
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	disconnected := make(chan struct{})
+	go func() {
+		tr.Disconnect()
+		close(disconnected)
+	}()
+	for _, c := range []chan struct{}{disconnected, done} {
+		select {
+		case <-c:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for Disconnect while the server is down")
+		}
+	}
+}
+
+func TestSinkReentrant(t *testing.T) {
+	tr := NewTracer(DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(SinkFunc(func(report []byte) {
+		// Sinks may call the Tracer's methods
+		tr.SetTailPolicy(nil)
+		tr.SetProcessName("reentrant")
+		sink.Report(report)
+	}))
+
+	NewTask()
+	done := make(chan struct{})
+	go func() {
+		tr.Log("foo")
+		tr.AddSink(&reporttest.Sink{})
+		tr.Log("bar")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out logging through a reentrant Sink")
+	}
+	if len(sink.Reports()) != 2 {
+		t.Fatalf("unexpected number of reports: got %v; want 2", len(sink.Reports()))
+	}
+	if name := sink.Reports()[1].ProcessName; name != "reentrant" {
+		t.Errorf("unexpected process name: got %v; want reentrant", name)
+	}
+}
@@ -2,6 +2,7 @@ package grpcutil
 
 import (
 	"fmt"
+	"github.com/brown-csci1380/tracing-framework-go/local"
 	xtr "github.com/brown-csci1380/tracing-framework-go/xtrace/client"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -11,6 +12,9 @@ import (
 
 // Handles propagation of x-trace metadata around grpc server requests (as the ServerOption to grpc.NewServer)
 var XTraceServerInterceptor grpc.UnaryServerInterceptor = func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	// gRPC runs handlers in goroutines which are not spawned
+	// with local.Go, so release their X-Trace state explicitly
+	defer local.Exit()
 	md, ok := metadata.FromIncomingContext(ctx)
 	//md, ok := metadata.FromContext(ctx)
 	if !ok {
@@ -24,12 +28,14 @@ var XTraceServerInterceptor grpc.UnaryServerInterceptor = func(ctx context.Conte
 	} else {
 		xtr.Logf("Returning from %s, response: %s", info.FullMethod, resp)
 	}
-	grpc.SetHeader(ctx, metadata.Pairs(GRPCMetadata()...))
+	// the caller's next event follows the end of the subtask
+	grpc.SetHeader(ctx, metadata.Pairs(metadataPairs(endSubtask(err))...))
 	return resp, err
 }
 
 // Handles propagation of x-trace metadata around grpc server stream RPCs (as a ServerOption to grpc.NewServer)
 var XTraceStreamServerInterceptor grpc.StreamServerInterceptor = func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	defer local.Exit()
 	md, ok := metadata.FromIncomingContext(ss.Context())
 	if !ok {
 		fmt.Fprintln(os.Stderr, "no metadata in request context.")
@@ -42,7 +48,7 @@ var XTraceStreamServerInterceptor grpc.StreamServerInterceptor = func(srv interf
 	} else {
 		xtr.Logf("Cread remote stream for %s, successful", info.FullMethod)
 	}
-	ss.SetHeader(metadata.Pairs(GRPCMetadata()...))
+	ss.SetHeader(metadata.Pairs(metadataPairs(endSubtask(err))...))
 	return err
 }
 
@@ -63,3 +69,12 @@ var XTraceStreamClientInterceptor grpc.StreamClientInterceptor = func(ctx contex
 	GRPCReturned(md, fmt.Sprintf("Recieved remote stream for %v: error: %v, stream: %v", method, err, cs))
 	return cs, err
 }
+
+// endSubtask ends the server-side subtask of a request whose
+// handler returned err, and returns the metadata to send back.
+func endSubtask(err error) xtr.RPCMetadata {
+	if err != nil {
+		return xtr.EndSubtaskRPC(xtr.StatusError)
+	}
+	return xtr.EndSubtaskRPC(xtr.StatusOK)
+}
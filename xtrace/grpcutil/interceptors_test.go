@@ -0,0 +1,150 @@
+package grpcutil
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/brown-csci1380/tracing-framework-go/local"
+	xtr "github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+)
+
+// testServer is a health server which calls
+// handle from each Check request's handler.
+type testServer struct {
+	*health.Server
+	handle func()
+}
+
+func (s *testServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s.handle()
+	return s.Server.Check(ctx, req)
+}
+
+// dialTestServer starts a server using the X-Trace interceptors
+// which calls handle from each request's handler, and returns a
+// client for it which also uses the X-Trace interceptors.
+func dialTestServer(t *testing.T, handle func()) healthpb.HealthClient {
+	l := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(XTraceServerInterceptor))
+	healthpb.RegisterHealthServer(srv, &testServer{health.NewServer(), handle})
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(XTraceClientInterceptor))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestTagsRoundTrip(t *testing.T) {
+	tags := []string{"ascii", "naïve,☃", "\x00\xff"}
+	got := make(chan []string, 1)
+	c := dialTestServer(t, func() { got <- xtr.GetTaskTags() })
+
+	xtr.NewTask(tags...)
+	if _, err := c.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recv := <-got
+	if len(recv) != len(tags) {
+		t.Fatalf("unexpected tags: got %q; want %q", recv, tags)
+	}
+	for i := range tags {
+		if recv[i] != tags[i] {
+			t.Errorf("unexpected tag %v: got %q; want %q", i, recv[i], tags[i])
+		}
+	}
+}
+
+func TestHandlerLocalsReleased(t *testing.T) {
+	c := dialTestServer(t, func() { xtr.Log("handling") })
+
+	xtr.NewTask()
+	start := local.LiveEntries()
+	for i := 0; i < 1000; i++ {
+		if _, err := c.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// handlers release their locals before their
+	// responses are sent, but allow for stragglers
+	for i := 0; i < 100 && local.LiveEntries() != start; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if got := local.LiveEntries(); got != start {
+		t.Errorf("unexpected live entries after requests: got %v; want %v", got, start)
+	}
+}
+
+func TestBaggageRoundTrip(t *testing.T) {
+	got := make(chan string, 1)
+	c := dialTestServer(t, func() {
+		v, _ := xtr.GetBaggage("request")
+		got <- v
+		xtr.SetBaggage("response", "b")
+	})
+
+	xtr.NewTask()
+	xtr.SetBaggage("request", "a")
+	if _, err := c.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := <-got; v != "a" {
+		t.Errorf("unexpected request baggage in handler: got %q; want %q", v, "a")
+	}
+	// the server's baggage is merged into the client's
+	if v, _ := xtr.GetBaggage("response"); v != "b" {
+		t.Errorf("unexpected response baggage: got %q; want %q", v, "b")
+	}
+	if v, _ := xtr.GetBaggage("request"); v != "a" {
+		t.Errorf("unexpected request baggage after call: got %q; want %q", v, "a")
+	}
+}
+
+func TestEndSubtaskLinked(t *testing.T) {
+	// reports are delivered in the goroutine which logs
+	// them, so the current event is the one being reported
+	var (
+		mtx    sync.Mutex
+		events []int64
+	)
+	xtr.DefaultTracer.AddSink(xtr.SinkFunc(func([]byte) {
+		mtx.Lock()
+		events = append(events, xtr.GetEventID())
+		mtx.Unlock()
+	}))
+	c := dialTestServer(t, func() {})
+
+	xtr.NewTask()
+	var md metadata.MD
+	if _, err := c.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Header(&md)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the server's last event is the end of its subtask, which
+	// is logged just before the client's response event
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(events) < 2 {
+		t.Fatalf("unexpected number of events: got %v; want at least 2", len(events))
+	}
+	end := strconv.FormatInt(events[len(events)-2], 10)
+	if got := md[EVENT_KEY]; len(got) != 1 || got[0] != end {
+		t.Errorf("unexpected events in response metadata: got %v; want [%v]", got, end)
+	}
+}
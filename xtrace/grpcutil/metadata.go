@@ -1,6 +1,7 @@
 package grpcutil
 
 import (
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/baggage"
 	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
 	"strconv"
 	"strings"
@@ -9,6 +10,17 @@ import (
 const EVENT_KEY = "events"
 const TASK_KEY = "task"
 const MD_KEY = "xtr_metadata"
+const SAMPLED_KEY = "sampled"
+const FORCE_KEY = "force"
+const TENANT_KEY = "tenant"
+
+// TAGS_KEY is a binary key, like BAGGAGE_KEY, since gRPC
+// rejects values of other keys which are not printable ASCII
+const TAGS_KEY = "tags-bin"
+
+// BAGGAGE_KEY is a binary key (its "-bin" suffix
+// tells gRPC to base64-encode the value on the wire)
+const BAGGAGE_KEY = "baggage-bin"
 
 func formatIDs(ids []int64) []string {
 	list := make([]string, len(ids))
@@ -27,15 +39,89 @@ func getIDs(ids string) []int64 {
 	return list
 }
 
+func formatBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// getBool returns whether the first value of md[key] is
+// true, or def if there is no value for key.
+func getBool(md map[string][]string, key string, def bool) bool {
+	strs, ok := md[key]
+	if !ok || len(strs) < 1 {
+		return def
+	}
+	b, err := strconv.ParseBool(strs[0])
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// getTenantClass returns the tenant class in md,
+// or 0 if there is none.
+func getTenantClass(md map[string][]string) int32 {
+	strs, ok := md[TENANT_KEY]
+	if !ok || len(strs) < 1 {
+		return 0
+	}
+	class, _ := strconv.ParseInt(strs[0], 10, 32)
+	return int32(class)
+}
+
+// getBaggage returns the baggage in md, or
+// nil if there is none or it is malformed.
+func getBaggage(md map[string][]string) baggage.ByteNamespaces {
+	strs, ok := md[BAGGAGE_KEY]
+	if !ok || len(strs) < 1 {
+		return nil
+	}
+	b := baggage.ByteNamespaces{}
+	if err := baggage.Unmarshal([]byte(strs[0]), b); err != nil {
+		return nil
+	}
+	return b
+}
+
 // Returns a slice of strings suitable for passing to grpc/metadata.Pairs
 func GRPCMetadata() []string {
-	r := client.GetRPCMetadata()
-	return []string{TASK_KEY, strconv.FormatInt(r.TaskID, 10), EVENT_KEY, strings.Join(formatIDs(r.Events), ",")}
+	return metadataPairs(client.GetRPCMetadata())
+}
+
+// metadataPairs returns the metadata pairs which carry r.
+func metadataPairs(r client.RPCMetadata) []string {
+	pairs := []string{TASK_KEY, strconv.FormatInt(r.TaskID, 10), EVENT_KEY, strings.Join(formatIDs(r.Events), ","),
+		SAMPLED_KEY, formatBool(!r.Unsampled), FORCE_KEY, formatBool(r.Forced),
+		TENANT_KEY, strconv.FormatInt(int64(r.TenantClass), 10)}
+	// each task tag is a separate value for TAGS_KEY,
+	// which is binary, so that tags may contain any
+	// characters (including commas and non-ASCII)
+	for _, tag := range r.Tags {
+		pairs = append(pairs, TAGS_KEY, tag)
+	}
+	if len(r.Baggage) > 0 {
+		if buf, err := baggage.Marshal(r.Baggage); err == nil {
+			pairs = append(pairs, BAGGAGE_KEY, string(buf))
+		}
+	}
+	return pairs
 }
 
+// GRPCRecieved sets the current goroutine's X-Trace state
+// from the metadata of an incoming request and logs msg.
+// If the request carries no X-Trace state but its FORCE_KEY
+// header is set (e.g., by a developer debugging a single
+// request), a new task is started and tracing is forced.
 func GRPCRecieved(md map[string][]string, msg string) {
+	forced := getBool(md, FORCE_KEY, false)
 	event_strs, ok := md[EVENT_KEY]
 	if !ok || len(event_strs) < 1 {
+		if forced {
+			client.NewTask()
+			client.ForceTrace()
+		}
 		client.Log(msg)
 		return
 	}
@@ -53,8 +139,13 @@ func GRPCRecieved(md map[string][]string, msg string) {
 	}
 
 	client.RPCReceived(client.RPCMetadata{
-		TaskID: taskID,
-		Events: events,
+		TaskID:      taskID,
+		Events:      events,
+		Unsampled:   !getBool(md, SAMPLED_KEY, true),
+		Forced:      forced,
+		TenantClass: getTenantClass(md),
+		Tags:        md[TAGS_KEY],
+		Baggage:     getBaggage(md),
 	}, msg)
 }
 
@@ -78,7 +169,9 @@ func GRPCReturned(md map[string][]string, msg string) {
 	}
 
 	client.RPCReturned(client.RPCMetadata{
-		TaskID: taskID,
-		Events: events,
+		TaskID:  taskID,
+		Events:  events,
+		Tags:    md[TAGS_KEY],
+		Baggage: getBaggage(md),
 	}, msg)
 }
@@ -0,0 +1,29 @@
+// Package logfields converts the structured fields of other logging
+// packages' log entries to X-Trace custom fields.
+package logfields
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+)
+
+// Flatten appends the values in m to fields in key order, qualifying
+// keys of nested maps with prefix and the keys of the maps which
+// contain them, separated by dots (e.g., "request.method").
+func Flatten(fields []client.Field, prefix string, m map[string]interface{}) []client.Field {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if mm, ok := m[k].(map[string]interface{}); ok {
+			fields = Flatten(fields, prefix+k+".", mm)
+			continue
+		}
+		fields = append(fields, client.Field{Key: prefix + k, Value: fmt.Sprint(m[k])})
+	}
+	return fields
+}
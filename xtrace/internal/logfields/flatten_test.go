@@ -0,0 +1,23 @@
+package logfields
+
+import (
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+)
+
+func TestFlatten(t *testing.T) {
+	fields := Flatten(nil, "p.", map[string]interface{}{
+		"b": "x",
+		"a": map[string]interface{}{"c": 1, "b": true},
+	})
+	expect := []client.Field{{Key: "p.a.b", Value: "true"}, {Key: "p.a.c", Value: "1"}, {Key: "p.b", Value: "x"}}
+	if len(fields) != len(expect) {
+		t.Fatalf("unexpected fields: got %v; want %v", fields, expect)
+	}
+	for i := range fields {
+		if fields[i] != expect[i] {
+			t.Errorf("unexpected field %v: got %v; want %v", i, fields[i], expect[i])
+		}
+	}
+}
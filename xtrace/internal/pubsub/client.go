@@ -13,9 +13,10 @@ import (
 // A Client represents a connection to a pubsub server.
 // The zero value is not a valid Client.
 type Client struct {
-	messages chan message
-	quit     chan struct{}
-	closed   uint32
+	messages  chan message
+	done      chan struct{} // closed by Close
+	closeOnce sync.Once
+	closed    uint32
 }
 
 // NewClient creates a new connection to server.
@@ -27,7 +28,7 @@ func NewClient(server string) (c *Client, err error) {
 
 	c = &Client{
 		messages: make(chan message, 1024),
-		quit:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
 	}
 	go c.daemon(server, conn)
 	return c, nil
@@ -37,7 +38,7 @@ func (c *Client) daemon(server string, conn net.Conn) {
 	for {
 		var m message
 		select {
-		case <-c.quit:
+		case <-c.done:
 			conn.Close()
 			return
 		case m = <-c.messages:
@@ -46,14 +47,19 @@ func (c *Client) daemon(server string, conn net.Conn) {
 		for {
 			err := writeMessage(conn, m)
 			if err == nil {
-				if m.wg != nil {
-					m.wg.Done()
+				if m.sent != nil {
+					close(m.sent)
 				}
 				break
 			}
 			fmt.Fprintf(os.Stderr, "pubsub client error: %v\n", err)
 
 			for {
+				select {
+				case <-c.done:
+					return
+				default:
+				}
 				conn, err = net.Dial("tcp", server)
 				if err == nil {
 					break
@@ -65,12 +71,11 @@ func (c *Client) daemon(server string, conn net.Conn) {
 }
 
 // Close closes the connection with the server.
+// Any calls to PublishBlock or TryPublishBlock which
+// are waiting for their messages to be written return.
 func (c *Client) Close() {
-	select {
-	case c.quit <- struct{}{}:
-	default:
-	}
 	atomic.StoreUint32(&c.closed, 1)
+	c.closeOnce.Do(func() { close(c.done) })
 }
 
 // Publish publishes msg on the given topic. Publish may block,
@@ -85,17 +90,33 @@ func (c *Client) Publish(topic, msg []byte) {
 }
 
 // PublishBlock is like Publish, except that it blocks until
-// the message has been written to the server. Note that this
-// does not guarantee receipt by the server.
+// the message has been written to the server, or until c is
+// closed. Note that this does not guarantee receipt by the server.
 func (c *Client) PublishBlock(topic, msg []byte) {
 	if atomic.LoadUint32(&c.closed) == 1 {
 		panic("publish on closed client")
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	c.messages <- message{topic: topic, message: msg, wg: &wg}
-	wg.Wait()
+	c.TryPublishBlock(topic, msg)
+}
+
+// TryPublishBlock is like PublishBlock, except that if c
+// is closed, either before or while the message is being
+// published, it returns instead of panicking. It reports
+// whether the message was written to the server.
+func (c *Client) TryPublishBlock(topic, msg []byte) bool {
+	m := message{topic: topic, message: msg, sent: make(chan struct{})}
+	select {
+	case c.messages <- m:
+	case <-c.done:
+		return false
+	}
+	select {
+	case <-m.sent:
+		return true
+	case <-c.done:
+		return false
+	}
 }
 
 // PublishString is equivalent to Publish([]byte(topic), []byte(msg)).
@@ -112,7 +133,7 @@ func (c *Client) PublishStringBlock(topic, msg string) {
 type message struct {
 	topic   []byte
 	message []byte
-	wg      *sync.WaitGroup // used by PublishBlock to block until sent
+	sent    chan struct{} // closed once written, if non-nil
 }
 
 func writeMessage(w io.Writer, m message) error {
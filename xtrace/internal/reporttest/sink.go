@@ -0,0 +1,155 @@
+// Package reporttest provides a Sink which records decoded X-Trace
+// reports, for the tests of the packages which log X-Trace events.
+package reporttest
+
+import (
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// A Report is a decoded XTraceReportv4 message
+// (see xtrace/client/internal/reporting.proto).
+type Report struct {
+	TaskID         int64
+	EventID        int64
+	ParentEventIDs []int64
+	Host           string
+	ProcessName    string
+	Agent          string
+	Label          string
+	Tags           []string
+	TenantClass    int32
+	// Fields are the report's custom fields,
+	// in the order they were recorded.
+	Fields []Field
+}
+
+// A Field is a custom field of a Report.
+type Field struct {
+	Key, Value string
+}
+
+// Field returns the value of the first custom
+// field with the given key, if any.
+func (r Report) Field(key string) (value string, ok bool) {
+	for _, f := range r.Fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Sink records every report it receives. It is safe
+// to use from multiple goroutines simultaneously.
+type Sink struct {
+	reports []Report
+	mtx     sync.Mutex
+}
+
+// Report decodes and records report. It
+// panics if report cannot be decoded.
+func (s *Sink) Report(report []byte) {
+	r, err := decode(report)
+	if err != nil {
+		panic(fmt.Errorf("reporttest: %v", err))
+	}
+	s.mtx.Lock()
+	s.reports = append(s.reports, r)
+	s.mtx.Unlock()
+}
+
+// Reports returns the reports recorded so far.
+func (s *Sink) Reports() []Report {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]Report(nil), s.reports...)
+}
+
+// field numbers of XTraceReportv4
+const (
+	taskIDField        = 1
+	eventIDField       = 2
+	parentEventIDField = 3
+	hostField          = 7
+	processNameField   = 9
+	agentField         = 12
+	labelField         = 14
+	keyField           = 15
+	valueField         = 16
+	tagsField          = 17
+	tenantClassField   = 18
+)
+
+func decode(b []byte) (Report, error) {
+	var (
+		r            Report
+		keys, values []string
+	)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return r, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case typ == protowire.Fixed64Type && (num == taskIDField || num == eventIDField || num == parentEventIDField):
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return r, protowire.ParseError(n)
+			}
+			b = b[n:]
+			switch num {
+			case taskIDField:
+				r.TaskID = int64(v)
+			case eventIDField:
+				r.EventID = int64(v)
+			default:
+				r.ParentEventIDs = append(r.ParentEventIDs, int64(v))
+			}
+		case typ == protowire.VarintType && num == tenantClassField:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return r, protowire.ParseError(n)
+			}
+			b = b[n:]
+			r.TenantClass = int32(v)
+		case typ == protowire.BytesType && num >= hostField && num <= tagsField:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return r, protowire.ParseError(n)
+			}
+			b = b[n:]
+			switch num {
+			case hostField:
+				r.Host = string(v)
+			case processNameField:
+				r.ProcessName = string(v)
+			case agentField:
+				r.Agent = string(v)
+			case labelField:
+				r.Label = string(v)
+			case keyField:
+				keys = append(keys, string(v))
+			case valueField:
+				values = append(values, string(v))
+			case tagsField:
+				r.Tags = append(r.Tags, string(v))
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return r, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	for i, k := range keys {
+		if i < len(values) {
+			r.Fields = append(r.Fields, Field{k, values[i]})
+		}
+	}
+	return r, nil
+}
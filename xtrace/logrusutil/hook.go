@@ -0,0 +1,78 @@
+// Package logrusutil provides a logrus Hook which
+// reports log entries as X-Trace events.
+package logrusutil
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+)
+
+// Hook is a logrus.Hook which logs each entry as an X-Trace event
+// under the current goroutine's task. The entry's message becomes
+// the event's label, its level is recorded as the event's Level, and
+// its data fields are recorded as custom fields. Add it to a logger
+// with AddHook:
+//
+//	logrus.AddHook(logrusutil.NewHook(nil, logrus.InfoLevel))
+type Hook struct {
+	tracer *client.Tracer
+	levels []logrus.Level
+}
+
+// NewHook creates a new Hook which logs entries at min or more
+// severe levels through t. If t is nil, client.DefaultTracer is used.
+func NewHook(t *client.Tracer, min logrus.Level) *Hook {
+	if t == nil {
+		t = client.DefaultTracer
+	}
+	h := &Hook{tracer: t}
+	for _, l := range logrus.AllLevels {
+		if l <= min {
+			h.levels = append(h.levels, l)
+		}
+	}
+	return h
+}
+
+// Levels returns the levels at which h logs entries.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire logs e as an X-Trace event.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	l := Level(e.Level)
+	if e.Caller != nil && !client.EnabledAt(e.Caller.PC, l) {
+		return nil
+	}
+
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]client.Field, len(keys))
+	for i, k := range keys {
+		fields[i] = client.Field{Key: k, Value: fmt.Sprint(e.Data[k])}
+	}
+	h.tracer.LogFields(l, e.Message, fields...)
+	return nil
+}
+
+// Level converts a logrus.Level to the corresponding client.Level.
+func Level(l logrus.Level) client.Level {
+	switch {
+	case l <= logrus.ErrorLevel:
+		return client.LevelError
+	case l == logrus.WarnLevel:
+		return client.LevelWarn
+	case l == logrus.InfoLevel:
+		return client.LevelInfo
+	default:
+		return client.LevelDebug
+	}
+}
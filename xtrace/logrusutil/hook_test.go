@@ -0,0 +1,83 @@
+package logrusutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestHookLevels(t *testing.T) {
+	levels := NewHook(nil, logrus.WarnLevel).Levels()
+	expect := []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}
+	if len(levels) != len(expect) {
+		t.Fatalf("unexpected levels: got %v; want %v", levels, expect)
+	}
+	for i := range expect {
+		if levels[i] != expect[i] {
+			t.Errorf("unexpected level %v: got %v; want %v", i, levels[i], expect[i])
+		}
+	}
+}
+
+func TestHookData(t *testing.T) {
+	tr := client.NewTracer(client.DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	client.NewTask()
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(NewHook(tr, logrus.InfoLevel))
+	// data fields are recorded in key order
+	logger.WithFields(logrus.Fields{"b": 1, "a": []int{2, 3}}).WithError(errors.New("test")).Warn("foo")
+
+	reports := sink.Reports()
+	if len(reports) != 1 {
+		t.Fatalf("unexpected number of reports: got %v; want 1", len(reports))
+	}
+	expect := []reporttest.Field{{Key: "level", Value: "warn"}, {Key: "a", Value: "[2 3]"}, {Key: "b", Value: "1"}, {Key: logrus.ErrorKey, Value: "test"}}
+	r := reports[0]
+	if len(r.Fields) != len(expect) {
+		t.Fatalf("unexpected fields: got %v; want %v", r.Fields, expect)
+	}
+	for i := range expect {
+		if r.Fields[i] != expect[i] {
+			t.Errorf("unexpected field %v: got %v; want %v", i, r.Fields[i], expect[i])
+		}
+	}
+}
+
+func TestHookPackageLevel(t *testing.T) {
+	defer client.ClearPackageLevels()
+	tr := client.NewTracer(client.DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	client.NewTask()
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(NewHook(tr, logrus.InfoLevel))
+	client.SetPackageLevel("github.com/brown-csci1380/tracing-framework-go/xtrace/logrusutil", client.LevelError)
+	// the caller's package is only known if logrus reports it
+	logger.SetReportCaller(true)
+	logger.Warn("dropped")
+	logger.Error("foo")
+	logger.SetReportCaller(false)
+	logger.Warn("bar")
+
+	reports := sink.Reports()
+	if len(reports) != 2 {
+		t.Fatalf("unexpected number of reports: got %v; want 2", len(reports))
+	}
+	if r := reports[0]; r.Label != "foo" || len(r.Tags) != 1 || r.Tags[0] != client.ErrorTag {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if r := reports[1]; r.Label != "bar" {
+		t.Errorf("unexpected report: %+v", r)
+	}
+}
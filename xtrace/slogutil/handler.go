@@ -0,0 +1,138 @@
+//go:build go1.21
+// +build go1.21
+
+// Package slogutil provides a log/slog Handler which
+// reports log records as X-Trace events.
+package slogutil
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+)
+
+// HandlerOptions are options for a Handler.
+// A zero HandlerOptions consists entirely of default values.
+type HandlerOptions struct {
+	// Tracer is the Tracer that records are logged through.
+	// If it is nil, client.DefaultTracer is used.
+	Tracer *client.Tracer
+
+	// Level is the minimum record level that will be logged.
+	// If it is nil, records are filtered only by the X-Trace
+	// client's global and per-package minimum Levels.
+	Level slog.Leveler
+}
+
+// Handler is a slog.Handler which logs each record as an X-Trace
+// event under the current goroutine's task. The record's message
+// becomes the event's label, its level is recorded as the event's
+// Level, and its attributes are recorded as custom fields. Attributes
+// in groups are recorded with keys qualified by the group names,
+// separated by dots (e.g., "request.method").
+type Handler struct {
+	tracer *client.Tracer
+	level  slog.Leveler
+	fields []client.Field
+	prefix string // qualifies keys of attributes added later
+}
+
+// NewHandler creates a new Handler. If opts is nil,
+// the default options are used.
+func NewHandler(opts *HandlerOptions) *Handler {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+	h := &Handler{tracer: opts.Tracer, level: opts.Level}
+	if h.tracer == nil {
+		h.tracer = client.DefaultTracer
+	}
+	return h
+}
+
+// Enabled reports whether h handles records at the given level.
+// Since the record's caller is not yet known, a level is enabled
+// if it is enabled for any package (see client.EnabledAnywhere);
+// Handle drops records which are not enabled for their caller.
+func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
+	if h.level != nil && l < h.level.Level() {
+		return false
+	}
+	return client.EnabledAnywhere(Level(l))
+}
+
+// Handle logs r as an X-Trace event.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	l := Level(r.Level)
+	if !client.EnabledAt(r.PC, l) {
+		return nil
+	}
+	fields := make([]client.Field, len(h.fields), len(h.fields)+r.NumAttrs())
+	copy(fields, h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, a)
+		return true
+	})
+	h.tracer.LogFields(l, r.Message, fields...)
+	return nil
+}
+
+// WithAttrs returns a new Handler whose records
+// include attrs in addition to h's attributes.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = make([]client.Field, len(h.fields), len(h.fields)+len(attrs))
+	copy(h2.fields, h.fields)
+	for _, a := range attrs {
+		h2.fields = appendAttr(h2.fields, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a new Handler which qualifies the
+// keys of attributes added later with the given group.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+func appendAttr(fields []client.Field, prefix string, a slog.Attr) []client.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range attrs {
+			fields = appendAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	return append(fields, client.Field{Key: prefix + a.Key, Value: a.Value.String()})
+}
+
+// Level converts a slog.Level to the corresponding client.Level.
+// Levels between the standard slog levels are rounded down.
+func Level(l slog.Level) client.Level {
+	switch {
+	case l >= slog.LevelError:
+		return client.LevelError
+	case l >= slog.LevelWarn:
+		return client.LevelWarn
+	case l >= slog.LevelInfo:
+		return client.LevelInfo
+	default:
+		return client.LevelDebug
+	}
+}
@@ -0,0 +1,77 @@
+//go:build go1.21
+// +build go1.21
+
+package slogutil
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestHandlerGroups(t *testing.T) {
+	tr := client.NewTracer(client.DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	client.NewTask()
+	logger := slog.New(NewHandler(&HandlerOptions{Tracer: tr}))
+	// groups qualify the attributes added after them, whether by
+	// With or by the record, and levels between the standard slog
+	// levels are rounded down
+	logger.With("a", 1).WithGroup("g").Log(context.Background(), slog.LevelInfo+2, "foo", "b", "x", slog.Group("h", "c", true))
+
+	reports := sink.Reports()
+	if len(reports) != 1 {
+		t.Fatalf("unexpected number of reports: got %v; want 1", len(reports))
+	}
+	expect := []reporttest.Field{{Key: "level", Value: "info"}, {Key: "a", Value: "1"}, {Key: "g.b", Value: "x"}, {Key: "g.h.c", Value: "true"}}
+	r := reports[0]
+	if len(r.Fields) != len(expect) {
+		t.Fatalf("unexpected fields: got %v; want %v", r.Fields, expect)
+	}
+	for i := range expect {
+		if r.Fields[i] != expect[i] {
+			t.Errorf("unexpected field %v: got %v; want %v", i, r.Fields[i], expect[i])
+		}
+	}
+}
+
+func TestAppendAttr(t *testing.T) {
+	fields := appendAttr(nil, "g.", slog.Group("h", "a", 1, slog.Group("", "b", "x"), slog.Group("empty")))
+	expect := []client.Field{{Key: "g.h.a", Value: "1"}, {Key: "g.h.b", Value: "x"}}
+	if len(fields) != len(expect) {
+		t.Fatalf("unexpected fields: got %v; want %v", fields, expect)
+	}
+	for i := range fields {
+		if fields[i] != expect[i] {
+			t.Errorf("unexpected field %v: got %v; want %v", i, fields[i], expect[i])
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	defer client.SetLevel(client.LevelInfo)
+	defer client.ClearPackageLevels()
+	ctx := context.Background()
+
+	h := NewHandler(nil)
+	client.SetLevel(client.LevelWarn)
+	if h.Enabled(ctx, slog.LevelInfo) || !h.Enabled(ctx, slog.LevelWarn) {
+		t.Errorf("global minimum level not applied")
+	}
+	// the caller is not known, so any package's
+	// minimum level may enable a record
+	client.SetPackageLevel("github.com/foo", client.LevelDebug)
+	if !h.Enabled(ctx, slog.LevelDebug) {
+		t.Errorf("package level not applied")
+	}
+
+	h = NewHandler(&HandlerOptions{Level: slog.LevelError})
+	if h.Enabled(ctx, slog.LevelWarn) || !h.Enabled(ctx, slog.LevelError) {
+		t.Errorf("handler level not applied")
+	}
+}
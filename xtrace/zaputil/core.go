@@ -0,0 +1,99 @@
+// Package zaputil provides a zap Core which reports
+// log entries as X-Trace events.
+package zaputil
+
+import (
+	"go.uber.org/zap/zapcore"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/logfields"
+)
+
+// Core is a zapcore.Core which logs each entry as an X-Trace event
+// under the current goroutine's task. The entry's message becomes the
+// event's label, its level is recorded as the event's Level, and its
+// fields are recorded as custom fields. Fields of nested objects are
+// recorded with keys qualified by the object keys and namespaces,
+// separated by dots (e.g., "request.method").
+//
+// Core is typically combined with an existing Core using
+// zapcore.NewTee, so that log entries are both written as
+// before and reported to X-Trace:
+//
+//	logger := zap.New(zapcore.NewTee(core, zaputil.NewCore(nil, zapcore.InfoLevel)))
+type Core struct {
+	tracer  *client.Tracer
+	enabler zapcore.LevelEnabler
+	// fields are the fields added by With. They are re-encoded
+	// along with each entry's fields, since a namespace opened
+	// by With applies to the fields which follow it.
+	fields []zapcore.Field
+}
+
+// NewCore creates a new Core which logs entries enabled by enabler
+// through t. If t is nil, client.DefaultTracer is used.
+func NewCore(t *client.Tracer, enabler zapcore.LevelEnabler) *Core {
+	if t == nil {
+		t = client.DefaultTracer
+	}
+	return &Core{tracer: t, enabler: enabler}
+}
+
+// Enabled reports whether c logs entries at the given level.
+func (c *Core) Enabled(l zapcore.Level) bool {
+	return c.enabler.Enabled(l)
+}
+
+// With returns a new Core whose entries include
+// fields in addition to c's fields.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	c2 := *c
+	c2.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &c2
+}
+
+// Check adds c to ce if c logs entries at ent's level. zap records
+// the caller of a logging call only after checking it, so Check
+// applies the per-package minimum Levels of every package, and Write
+// applies those of the caller's package (if recorded).
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) && client.EnabledAnywhere(Level(ent.Level)) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write logs ent and fields as an X-Trace event.
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !client.EnabledAt(ent.Caller.PC, Level(ent.Level)) {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	c.tracer.LogFields(Level(ent.Level), ent.Message, logfields.Flatten(nil, "", enc.Fields)...)
+	return nil
+}
+
+// Sync is a no-op; events are reported when they are written.
+func (c *Core) Sync() error {
+	return nil
+}
+
+// Level converts a zapcore.Level to the corresponding client.Level.
+func Level(l zapcore.Level) client.Level {
+	switch {
+	case l >= zapcore.ErrorLevel:
+		return client.LevelError
+	case l >= zapcore.WarnLevel:
+		return client.LevelWarn
+	case l >= zapcore.InfoLevel:
+		return client.LevelInfo
+	default:
+		return client.LevelDebug
+	}
+}
@@ -0,0 +1,71 @@
+package zaputil
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestCoreWith(t *testing.T) {
+	tr := client.NewTracer(client.DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	client.NewTask()
+	base := zap.New(NewCore(tr, zapcore.InfoLevel)).With(zap.String("a", "b"))
+	// a namespace opened by With applies to fields added later,
+	// including those of each entry
+	ns := base.With(zap.Namespace("ns"), zap.Int("n", 1))
+	ns.With(zap.String("c", "d")).Warn("foo", zap.Bool("e", true))
+	base.Info("bar", zap.Int("n", 2))
+
+	reports := sink.Reports()
+	if len(reports) != 2 {
+		t.Fatalf("unexpected number of reports: got %v; want 2", len(reports))
+	}
+	expect := [][]reporttest.Field{
+		{{Key: "level", Value: "warn"}, {Key: "a", Value: "b"}, {Key: "ns.c", Value: "d"}, {Key: "ns.e", Value: "true"}, {Key: "ns.n", Value: "1"}},
+		{{Key: "level", Value: "info"}, {Key: "a", Value: "b"}, {Key: "n", Value: "2"}},
+	}
+	for i, r := range reports {
+		if len(r.Fields) != len(expect[i]) {
+			t.Errorf("unexpected fields in report %v: got %v; want %v", i, r.Fields, expect[i])
+			continue
+		}
+		for j := range expect[i] {
+			if r.Fields[j] != expect[i][j] {
+				t.Errorf("unexpected field %v in report %v: got %v; want %v", j, i, r.Fields[j], expect[i][j])
+			}
+		}
+	}
+}
+
+func TestCorePackageLevel(t *testing.T) {
+	defer client.ClearPackageLevels()
+	tr := client.NewTracer(client.DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	client.NewTask()
+	core := NewCore(tr, zapcore.DebugLevel)
+	client.SetPackageLevel("github.com/brown-csci1380/tracing-framework-go/xtrace/zaputil", client.LevelError)
+	// the caller's package is only known if zap records it
+	zap.New(core, zap.AddCaller()).Warn("dropped")
+	zap.New(core, zap.AddCaller()).Error("foo")
+	zap.New(core).Warn("bar")
+
+	reports := sink.Reports()
+	if len(reports) != 2 {
+		t.Fatalf("unexpected number of reports: got %v; want 2", len(reports))
+	}
+	if r := reports[0]; r.Label != "foo" || len(r.Tags) != 1 || r.Tags[0] != client.ErrorTag {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if r := reports[1]; r.Label != "bar" {
+		t.Errorf("unexpected report: %+v", r)
+	}
+}
@@ -0,0 +1,98 @@
+// Package zerologutil provides a zerolog writer which
+// reports log events as X-Trace events.
+package zerologutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/logfields"
+)
+
+// Writer is a zerolog.LevelWriter which logs each JSON log event
+// written to it as an X-Trace event under the current goroutine's
+// task. The event's message becomes the X-Trace event's label, its
+// level is recorded as the X-Trace event's Level, and its other fields
+// are recorded as custom fields. Fields of nested objects are recorded
+// with keys qualified by the object keys, separated by dots.
+//
+// Writer is typically combined with an existing writer using
+// zerolog.MultiLevelWriter:
+//
+//	logger := zerolog.New(zerolog.MultiLevelWriter(os.Stderr, zerologutil.NewWriter(nil, zerolog.InfoLevel)))
+//
+// zerolog passes only the encoded event to its writers, not the
+// program counter of the logging call, so per-package Levels set with
+// client.SetPackageLevel cannot be applied; events are filtered by
+// min and the client's global minimum Level only.
+type Writer struct {
+	tracer *client.Tracer
+	min    zerolog.Level
+}
+
+// NewWriter creates a new Writer which logs events at min or more
+// severe levels through t. If t is nil, client.DefaultTracer is used.
+func NewWriter(t *client.Tracer, min zerolog.Level) *Writer {
+	if t == nil {
+		t = client.DefaultTracer
+	}
+	return &Writer{tracer: t, min: min}
+}
+
+// Write logs p, which must be a single JSON log event, at
+// the level recorded in its level field (if any).
+func (w *Writer) Write(p []byte) (n int, err error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel logs p, which must be a single JSON
+// log event, at the given level.
+func (w *Writer) WriteLevel(l zerolog.Level, p []byte) (n int, err error) {
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return 0, fmt.Errorf("zerologutil: %v", err)
+	}
+
+	if l == zerolog.NoLevel {
+		if s, ok := m[zerolog.LevelFieldName].(string); ok {
+			if pl, err := zerolog.ParseLevel(s); err == nil {
+				l = pl
+			}
+		}
+	}
+	delete(m, zerolog.LevelFieldName)
+	if l < w.min || l == zerolog.Disabled {
+		return len(p), nil
+	}
+	cl := Level(l)
+	if !client.EnabledAt(0, cl) {
+		return len(p), nil
+	}
+
+	msg, _ := m[zerolog.MessageFieldName].(string)
+	delete(m, zerolog.MessageFieldName)
+	w.tracer.LogFields(cl, msg, logfields.Flatten(nil, "", m)...)
+	return len(p), nil
+}
+
+// Level converts a zerolog.Level to the corresponding client.Level.
+func Level(l zerolog.Level) client.Level {
+	switch {
+	case l == zerolog.NoLevel:
+		return client.LevelInfo
+	case l >= zerolog.ErrorLevel:
+		return client.LevelError
+	case l == zerolog.WarnLevel:
+		return client.LevelWarn
+	case l == zerolog.InfoLevel:
+		return client.LevelInfo
+	default:
+		return client.LevelDebug
+	}
+}
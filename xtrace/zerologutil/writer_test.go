@@ -0,0 +1,70 @@
+package zerologutil
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/client"
+	"github.com/brown-csci1380/tracing-framework-go/xtrace/internal/reporttest"
+)
+
+func TestWriterLevel(t *testing.T) {
+	tr := client.NewTracer(client.DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	client.NewTask()
+	w := NewWriter(tr, zerolog.InfoLevel)
+	// Write takes the level from the event's level
+	// field; WriteLevel takes it from its caller
+	w.Write([]byte(`{"level":"debug","message":"dropped"}`))
+	w.Write([]byte(`{"level":"warn","message":"foo"}`))
+	w.WriteLevel(zerolog.ErrorLevel, []byte(`{"level":"info","message":"bar"}`))
+	// events without a level are logged at LevelInfo
+	logger := zerolog.New(w)
+	logger.Log().Msg("baz")
+
+	reports := sink.Reports()
+	expect := []struct{ label, level string }{{"foo", "warn"}, {"bar", "error"}, {"baz", "info"}}
+	if len(reports) != len(expect) {
+		t.Fatalf("unexpected number of reports: got %v; want %v", len(reports), len(expect))
+	}
+	for i, r := range reports {
+		if level, _ := r.Field("level"); r.Label != expect[i].label || level != expect[i].level {
+			t.Errorf("unexpected report %v: got %+v; want label %v and level %v", i, r, expect[i].label, expect[i].level)
+		}
+	}
+}
+
+func TestWriterFields(t *testing.T) {
+	tr := client.NewTracer(client.DefaultServerString)
+	var sink reporttest.Sink
+	tr.AddSink(&sink)
+
+	client.NewTask()
+	logger := zerolog.New(NewWriter(tr, zerolog.InfoLevel))
+	// numbers are recorded as written, not as float64s
+	logger.Warn().Int64("n", 1<<60).Dict("d", zerolog.Dict().Str("c", "x")).Msg("foo")
+
+	reports := sink.Reports()
+	if len(reports) != 1 {
+		t.Fatalf("unexpected number of reports: got %v; want 1", len(reports))
+	}
+	expect := []reporttest.Field{{Key: "level", Value: "warn"}, {Key: "d.c", Value: "x"}, {Key: "n", Value: "1152921504606846976"}}
+	r := reports[0]
+	if len(r.Fields) != len(expect) {
+		t.Fatalf("unexpected fields: got %v; want %v", r.Fields, expect)
+	}
+	for i := range expect {
+		if r.Fields[i] != expect[i] {
+			t.Errorf("unexpected field %v: got %v; want %v", i, r.Fields[i], expect[i])
+		}
+	}
+}
+
+func TestWriterInvalid(t *testing.T) {
+	if _, err := NewWriter(nil, zerolog.InfoLevel).Write([]byte("not json")); err == nil {
+		t.Errorf("unexpected nil error writing invalid event")
+	}
+}